@@ -0,0 +1,297 @@
+package program
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Structures can be bound to a program or a command: tagged fields are used to
+// declare options and arguments, and are set when the command line is parsed.
+//
+// Options are declared with the "option" tag containing the short and long
+// name of the option separated by a comma, e.g. `option:"n,dry-run"`.
+// Arguments are declared with the "argument" tag containing the name of the
+// argument, optionally followed by ",optional" or ",trailing".
+//
+// The "description", "value" and "default" tags are used to set the
+// description, value name and default value of options and arguments. The
+// "group" tag sets the group of an option.
+//
+// Boolean fields are used for flags. Slice fields are used for repeatable
+// options and trailing arguments. Fields whose address implements the Value
+// interface are used as typed value; they are not zeroed between command lines
+// but reset if they implement ResettableValue, so that settings such as the
+// list of values of an EnumValue are preserved.
+
+type binding struct {
+	field  reflect.Value
+	option *Option
+}
+
+type fieldValue struct {
+	field reflect.Value
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+func (p *Program) Bind(v any) {
+	p.bind(nil, v)
+}
+
+func (c *Command) Bind(v any) {
+	c.program.bind(c, v)
+}
+
+func (p *Program) bind(c *Command, v any) {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Pointer || ptr.Elem().Kind() != reflect.Struct {
+		Panic("cannot bind value of type %T: must be a pointer to a structure",
+			v)
+	}
+
+	s := ptr.Elem()
+
+	for i := range s.NumField() {
+		fieldType := s.Type().Field(i)
+		field := s.Field(i)
+
+		if tag, found := fieldType.Tag.Lookup("option"); found {
+			p.bindOption(c, fieldType, field, tag)
+		} else if tag, found := fieldType.Tag.Lookup("argument"); found {
+			p.bindArgument(c, fieldType, field, tag)
+		}
+	}
+}
+
+func (p *Program) bindOption(c *Command, fieldType reflect.StructField, field reflect.Value, tag string) {
+	if !fieldType.IsExported() {
+		Panic("cannot bind unexported field %q", fieldType.Name)
+	}
+
+	shortName, longName, _ := strings.Cut(tag, ",")
+
+	opt := Option{
+		ShortName:    shortName,
+		LongName:     longName,
+		ValueName:    fieldType.Tag.Get("value"),
+		DefaultValue: fieldType.Tag.Get("default"),
+		Description:  fieldType.Tag.Get("description"),
+		Group:        fieldType.Tag.Get("group"),
+	}
+
+	if field.Kind() == reflect.Bool {
+		p.bindings = append(p.bindings, &binding{field: field, option: &opt})
+	} else {
+		opt.TypedValue = p.bindValue(fieldType, field)
+		opt.Repeatable = field.Kind() == reflect.Slice
+	}
+
+	p.addOption(c, &opt)
+}
+
+func (p *Program) bindArgument(c *Command, fieldType reflect.StructField, field reflect.Value, tag string) {
+	if !fieldType.IsExported() {
+		Panic("cannot bind unexported field %q", fieldType.Name)
+	}
+
+	name, kind, _ := strings.Cut(tag, ",")
+	description := fieldType.Tag.Get("description")
+
+	var arg *Argument
+
+	switch kind {
+	case "":
+		if c == nil {
+			arg = p.AddArgument(name, description)
+		} else {
+			arg = c.AddArgument(name, description)
+		}
+
+	case "optional":
+		if c == nil {
+			arg = p.AddOptionalArgument(name, description)
+		} else {
+			arg = c.AddOptionalArgument(name, description)
+		}
+
+	case "trailing":
+		if field.Kind() != reflect.Slice {
+			Panic("invalid field %q for trailing argument %q: must be a slice",
+				fieldType.Name, name)
+		}
+
+		if c == nil {
+			arg = p.AddTrailingArgument(name, description)
+		} else {
+			arg = c.AddTrailingArgument(name, description)
+		}
+
+	default:
+		Panic("invalid argument tag %q for field %q", tag, fieldType.Name)
+	}
+
+	arg.TypedValue = p.bindValue(fieldType, field)
+}
+
+func (p *Program) bindValue(fieldType reflect.StructField, field reflect.Value) Value {
+	if v, ok := field.Addr().Interface().(Value); ok {
+		return v
+	}
+
+	t := field.Type()
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	if _, err := reflectTypeName(t); err != nil {
+		Panic("cannot bind field %q: %v", fieldType.Name, err)
+	}
+
+	p.bindings = append(p.bindings, &binding{field: field})
+
+	return &fieldValue{field: field}
+}
+
+func (p *Program) resetBindings() {
+	for _, b := range p.bindings {
+		b.field.SetZero()
+	}
+}
+
+func (p *Program) applyBindings() {
+	for _, b := range p.bindings {
+		if b.option != nil {
+			b.field.SetBool(b.option.flagValue())
+		}
+	}
+}
+
+func (v *fieldValue) Parse(s string) error {
+	if v.field.Kind() == reflect.Slice {
+		elem := reflect.New(v.field.Type().Elem()).Elem()
+		if err := parseReflectValue(elem, s); err != nil {
+			return err
+		}
+
+		v.field.Set(reflect.Append(v.field, elem))
+		return nil
+	}
+
+	return parseReflectValue(v.field, s)
+}
+
+func (v *fieldValue) String() string {
+	return fmt.Sprintf("%v", v.field.Interface())
+}
+
+func (v *fieldValue) TypeName() string {
+	t := v.field.Type()
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	name, _ := reflectTypeName(t)
+	return name
+}
+
+func reflectTypeName(t reflect.Type) (string, error) {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return strings.ToLower(t.Name()), nil
+	}
+
+	if t == durationType {
+		return "duration", nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string", nil
+
+	case reflect.Bool:
+		return "boolean", nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64:
+		return "integer", nil
+
+	case reflect.Float32, reflect.Float64:
+		return "number", nil
+	}
+
+	return "", fmt.Errorf("unsupported type %v", t)
+}
+
+func parseReflectValue(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	if v.Type() == durationType {
+		d, err := parseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+
+	case reflect.Bool:
+		b, err := parseBoolean(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		bits := v.Type().Bits()
+		max := int64(1)<<(bits-1) - 1
+
+		i, err := parseInteger(s, -max-1, max)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		bits := v.Type().Bits()
+
+		i, err := strconv.ParseUint(s, 10, bits)
+		if err != nil {
+			max := uint64(math.MaxUint64) >> (64 - bits)
+			return fmt.Errorf("must be an integer between 0 and %d", max)
+		}
+
+		v.SetUint(i)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := parseFloat(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+
+	return nil
+}
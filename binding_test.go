@@ -0,0 +1,75 @@
+package program
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBind(t *testing.T) {
+	assert := assert.New(t)
+
+	var globalOptions struct {
+		Verbose bool `option:"v,verbose" description:"print more"`
+	}
+
+	var options struct {
+		DryRun   bool          `option:"n,dry-run"`
+		Count    uint8         `option:"c,count" default:"3"`
+		Timeout  time.Duration `option:",timeout" value:"delay"`
+		Ratio    float64       `option:"r,"`
+		Includes []string      `option:"I,include" value:"path"`
+		Format   EnumValue     `option:"f,format"`
+		Address  netip.Addr    `argument:"address"`
+		Name     string        `argument:"name,optional"`
+		Ports    []int         `argument:"port,trailing"`
+
+		ignored string
+	}
+
+	options.Format.Values = []string{"json", "text"}
+
+	p := NewProgram("test", "")
+	p.Bind(&globalOptions)
+
+	c := p.AddCommand("foo", "", func(p *Program) {})
+	c.Bind(&options)
+
+	args := []string{"-v", "foo", "-n", "--timeout=1m", "-r", "0.5",
+		"-Ia", "-Ib", "-ftext", "10.0.0.1", "bar", "80", "443"}
+	if assert.NoError(p.ParseArgs(args)) {
+		assert.True(globalOptions.Verbose)
+		assert.True(options.DryRun)
+		assert.Equal(uint8(3), options.Count)
+		assert.Equal(time.Minute, options.Timeout)
+		assert.Equal(0.5, options.Ratio)
+		assert.Equal([]string{"a", "b"}, options.Includes)
+		assert.Equal("text", options.Format.Value)
+		assert.Equal(netip.MustParseAddr("10.0.0.1"), options.Address)
+		assert.Equal("bar", options.Name)
+		assert.Equal([]int{80, 443}, options.Ports)
+	}
+
+	if assert.NoError(p.ParseArgs([]string{"foo", "-c", "5", "::1"})) {
+		assert.False(globalOptions.Verbose)
+		assert.False(options.DryRun)
+		assert.Equal(uint8(5), options.Count)
+		assert.Equal(time.Duration(0), options.Timeout)
+		assert.Nil(options.Includes)
+		assert.Equal("", options.Format.Value)
+		assert.Equal([]string{"json", "text"}, options.Format.Values)
+		assert.Equal(netip.MustParseAddr("::1"), options.Address)
+		assert.Equal("", options.Name)
+		assert.Nil(options.Ports)
+	}
+
+	err := p.ParseArgs([]string{"foo", "-c", "256", "::1"})
+	assert.EqualError(err, `invalid value "256" for option "c": `+
+		`must be an integer between 0 and 255`)
+
+	err = p.ParseArgs([]string{"foo", "::1", "bar", "http"})
+	assert.EqualError(err, `invalid value "http" for argument "port": `+
+		`must be an integer`)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"go.n16f.net/program"
+)
+
+var globalOptions struct {
+	Verbose bool `option:"v,verbose" description:"print more information"`
+}
+
+var createOptions struct {
+	DryRun  bool          `option:"n,dry-run" description:"only pretend to create the foo"`
+	Timeout time.Duration `option:"t,timeout" value:"duration" default:"10s" description:"the maximum time to wait for the creation"`
+	Labels  []string      `option:"l,label" value:"name" description:"a label to associate with the foo"`
+	Name    string        `argument:"name" description:"the name of the foo"`
+	Count   int           `argument:"count,optional" description:"the number of foos to create"`
+}
+
+func main() {
+	p := program.NewProgram("binding",
+		"an example program using structure binding")
+
+	p.Bind(&globalOptions)
+
+	c := p.AddCommand("create", "create a foo", cmdCreate)
+	c.Bind(&createOptions)
+
+	p.ParseCommandLine()
+	p.Run()
+}
+
+func cmdCreate(p *program.Program) {
+	p.Info("running command %q", p.CommandFullName())
+
+	t := program.NewKeyValueTable()
+	t.AddRow("verbose", globalOptions.Verbose)
+	t.AddRow("dry-run", createOptions.DryRun)
+	t.AddRow("timeout", createOptions.Timeout)
+	t.AddRow("labels", strings.Join(createOptions.Labels, " "))
+	t.AddRow("name", createOptions.Name)
+	t.AddRow("count", createOptions.Count)
+	p.PrintTable(t)
+}
@@ -42,7 +42,7 @@ func cmdFoo(p *program.Program) {
 	t.AddRow("arg-1", p.ArgumentValue("arg-1"))
 	t.AddRow("arg-2", p.ArgumentValue("arg-2"))
 	t.AddRow("arg-3", strings.Join(p.TrailingArgumentValues("arg-3"), " "))
-	t.Print()
+	p.PrintTable(t)
 }
 
 func cmdBar(p *program.Program) {
@@ -53,11 +53,11 @@ func cmdBar(p *program.Program) {
 	t.AddRow("b", p.IsOptionSet("b"))
 	t.AddRow("option-c", p.OptionValue("option-c"))
 	t.AddRow("arg-opt", p.ArgumentValue("arg-opt"))
-	t.Print()
+	p.PrintTable(t)
 
-	fmt.Printf("flag-a: %v\n", p.IsOptionSet("flag-a"))
-	fmt.Printf("b: %v\n", p.IsOptionSet("b"))
-	fmt.Printf("option-c: %s\n", p.OptionValue("option-c"))
+	fmt.Fprintf(p.Stdout, "flag-a: %v\n", p.IsOptionSet("flag-a"))
+	fmt.Fprintf(p.Stdout, "b: %v\n", p.IsOptionSet("b"))
+	fmt.Fprintf(p.Stdout, "option-c: %s\n", p.OptionValue("option-c"))
 
-	fmt.Printf("arg-opt: %s\n", p.ArgumentValue("arg-opt"))
+	fmt.Fprintf(p.Stdout, "arg-opt: %s\n", p.ArgumentValue("arg-opt"))
 }
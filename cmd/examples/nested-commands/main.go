@@ -15,7 +15,7 @@ func main() {
 	p.AddFlag("a", "", "a short flag")
 
 	c = p.AddCommand("foo create", "create a foo", cmdFooCreate)
-	c.AddFlag("-n", "dry-run", "only pretend to create the foo")
+	c.AddFlag("n", "dry-run", "only pretend to create the foo")
 	c.AddArgument("name", "the name of the foo")
 	c.AddTrailingArgument("option", "a creation option")
 
@@ -37,7 +37,7 @@ func cmdFooCreate(p *program.Program) {
 	t.AddRow("dry-run", p.IsOptionSet("dry-run"))
 	t.AddRow("name", p.ArgumentValue("name"))
 	t.AddRow("options", strings.Join(p.TrailingArgumentValues("option"), " "))
-	t.Print()
+	p.PrintTable(t)
 }
 
 func cmdFooDelete(p *program.Program) {
@@ -46,7 +46,7 @@ func cmdFooDelete(p *program.Program) {
 	t := program.NewKeyValueTable()
 	t.AddRow("a", p.IsOptionSet("a"))
 	t.AddRow("name", p.ArgumentValue("name"))
-	t.Print()
+	p.PrintTable(t)
 }
 
 func cmdBar(p *program.Program) {
@@ -55,5 +55,5 @@ func cmdBar(p *program.Program) {
 	t := program.NewKeyValueTable()
 	t.AddRow("a", p.IsOptionSet("a"))
 	t.AddRow("arg-opt", p.ArgumentValue("arg-opt"))
-	t.Print()
+	p.PrintTable(t)
 }
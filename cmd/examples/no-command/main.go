@@ -41,5 +41,5 @@ func main2(p *program.Program) {
 	t.AddRow("arg-opt-2", p.ArgumentValue("arg-opt-2"))
 	t.AddRow("arg-trailing",
 		strings.Join(p.TrailingArgumentValues("arg-trailing"), " "))
-	t.Print()
+	p.PrintTable(t)
 }
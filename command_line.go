@@ -1,12 +1,17 @@
 package program
 
 import (
+	"fmt"
 	"math"
+	"net/netip"
+	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"go.n16f.net/uuid"
 )
@@ -18,23 +23,125 @@ type Command struct {
 	FullName    string
 	Description string
 	Main        Main
+	ParsingMode ParsingMode
+
+	// Hidden commands can be used but are not listed in usage information.
+	Hidden bool
+
+	// Alternative names which can be used to select the command, e.g. "rm"
+	// for a "delete" command.
+	Aliases []string
+
+	// If set, using the command prints a warning containing this message,
+	// e.g. `use "foo remove" instead`.
+	Deprecated string
+
+	// Additional documentation displayed in the help message of the command
+	// and in generated documentation. Paragraphs of the long description are
+	// separated by empty lines; see also references are full command names.
+	LongDescription string
+	Examples        []CommandExample
+	SeeAlso         []string
 
 	program *Program
+	parent  *Command
+
+	// Default commands such as "help" must work even if options required by
+	// the program are not set.
+	isDefault bool
 
 	subcommands map[string]*Command
 	options     map[string]*Option
 	arguments   []*Argument
+
+	optionConstraints []*optionConstraint
 }
 
-func (p *Program) newCommandGroup(name, fullName string) *Command {
+type CommandExample struct {
+	// The command line without the program name, e.g. "foo create bar".
+	CommandLine string
+	Description string
+}
+
+func (p *Program) newCommandGroup(parent *Command, name, fullName string) *Command {
 	return &Command{
 		Name:     name,
 		FullName: fullName,
 
 		program: p,
+		parent:  parent,
 
 		subcommands: make(map[string]*Command),
+		options:     make(map[string]*Option),
+	}
+}
+
+// Return all options available for the command, i.e. options of the command
+// and of all groups containing it, without global options.
+func (c *Command) allOptions() map[string]*Option {
+	options := make(map[string]*Option)
+
+	for c2 := c; c2 != nil; c2 = c2.parent {
+		for name, opt := range c2.options {
+			if _, found := options[name]; !found {
+				options[name] = opt
+			}
+		}
+	}
+
+	return options
+}
+
+func (c *Command) findSubcommand(name string) *Command {
+	if cmd := c.subcommands[name]; cmd != nil {
+		return cmd
+	}
+
+	for _, cmd := range c.subcommands {
+		if slices.Contains(cmd.Aliases, name) {
+			return cmd
+		}
+	}
+
+	if !c.program.CommandPrefixMatching || name == "" {
+		return nil
+	}
+
+	var match *Command
+
+	for _, cmd := range c.subcommands {
+		if cmd.Hidden {
+			continue
+		}
+
+		for _, cmdName := range cmd.names() {
+			if strings.HasPrefix(cmdName, name) {
+				if match != nil && match != cmd {
+					return nil // ambiguous prefix
+				}
+
+				match = cmd
+			}
+		}
+	}
+
+	return match
+}
+
+func (c *Command) subcommandSuggestions(name string) []string {
+	var candidates []string
+
+	for _, cmd := range c.subcommands {
+		if !cmd.Hidden {
+			candidates = append(candidates, cmd.names()...)
+		}
 	}
+
+	return suggestions(name, candidates)
+}
+
+func (c *Command) names() []string {
+	return append([]string{c.Name}, c.Aliases...)
 }
 
 func (c *Command) Label() string {
@@ -52,8 +159,123 @@ type Option struct {
 	DefaultValue string
 	Description  string
 
-	Set   bool
-	Value string
+	// The environment variable used to set the option if it is not set on
+	// the command line. If empty, the name is derived from the long name of
+	// the option if the program has an environment variable prefix.
+	EnvironmentVariable string
+
+	// If set, the typed value is used to parse every value of the option
+	// when the command line is parsed. Options with a typed value always
+	// take a value.
+	TypedValue Value
+
+	// Repeatable options can be used multiple times; every value is stored
+	// in Values. Repeatable flags are counted (e.g. "-vvv").
+	Repeatable bool
+
+	// Negatable flags can be set to false with "--no-<long name>", e.g. to
+	// override a value set in the environment. Flags whose default value is
+	// "true" are always negatable.
+	Negatable bool
+
+	// Used to complete the value of the option. If Complete is not set,
+	// candidates are taken from enumeration typed values.
+	Complete       CompletionFunc
+	CompletionHint CompletionHint
+
+	// Options with a group are listed in usage information in a separate
+	// section whose title is the name of the group, e.g. "Network".
+	Group string
+
+	// Hidden options can be used but are not listed in usage information.
+	Hidden bool
+
+	// If set, using the option prints a warning containing this message,
+	// e.g. `use "--output" instead`.
+	Deprecated string
+
+	// Required options must be set on the command line, in the environment
+	// or in a configuration file.
+	Required bool
+
+	// The position of the option in declaration order.
+	index int
+
+	Set    bool
+	Value  string
+	Values []string
+	Count  int
+	Origin OptionOrigin
+}
+
+func (opt *Option) Name() string {
+	if opt.LongName != "" {
+		return opt.LongName
+	}
+
+	return opt.ShortName
+}
+
+func (opt *Option) takesValue() bool {
+	return opt.ValueName != "" || opt.TypedValue != nil
+}
+
+func (opt *Option) valueName() string {
+	if opt.ValueName == "" && opt.TypedValue != nil {
+		return opt.TypedValue.TypeName()
+	}
+
+	return opt.ValueName
+}
+
+func (opt *Option) isNegatable() bool {
+	return !opt.takesValue() && opt.LongName != "" &&
+		(opt.Negatable || opt.DefaultValue == "true")
+}
+
+func (opt *Option) set(origin OptionOrigin) {
+	opt.Set = true
+	opt.Count++
+	opt.Origin = origin
+}
+
+func (opt *Option) setFlag(value bool, origin OptionOrigin) {
+	// The value of negatable flags is stored so that they can be explicitly
+	// false.
+	opt.set(origin)
+
+	if opt.isNegatable() {
+		opt.Value = strconv.FormatBool(value)
+		opt.Values = []string{opt.Value}
+	}
+}
+
+func (opt *Option) flagValue() bool {
+	if !opt.Set {
+		return opt.DefaultValue == "true"
+	}
+
+	return opt.Value != "false"
+}
+
+func (opt *Option) setValue(value string, origin OptionOrigin) error {
+	if opt.TypedValue != nil {
+		if err := opt.TypedValue.Parse(value); err != nil {
+			return err
+		}
+	}
+
+	opt.set(origin)
+
+	opt.Value = value
+
+	if opt.Repeatable {
+		opt.Values = append(opt.Values, value)
+	} else {
+		opt.Values = []string{value}
+	}
+
+	return nil
 }
 
 type Argument struct {
@@ -62,43 +284,108 @@ type Argument struct {
 	Optional    bool
 	Trailing    bool
 
+	// If set, the typed value is used to parse the value of the argument, or
+	// every value for trailing arguments, when the command line is parsed.
+	TypedValue Value
+
+	// Used to complete the value of the argument. If Complete is not set,
+	// candidates are taken from enumeration typed values.
+	Complete       CompletionFunc
+	CompletionHint CompletionHint
+
 	Set            bool
 	Value          string
 	TrailingValues []string
 }
 
-func (p *Program) AddCommand(fullName, description string, main Main) *Command {
+func (arg *Argument) usageString() string {
+	if arg.Trailing {
+		return "[<" + arg.Name + ">...]"
+	} else if arg.Optional {
+		return "[<" + arg.Name + ">]"
+	}
+
+	return "<" + arg.Name + ">"
+}
+
+func (arg *Argument) setValue(value string) error {
+	if arg.TypedValue != nil {
+		if err := arg.TypedValue.Parse(value); err != nil {
+			return &InvalidArgumentValueError{
+				Argument: arg.Name,
+				Value:    value,
+				Err:      err,
+			}
+		}
+	}
+
+	arg.Set = true
+
+	if arg.Trailing {
+		arg.TrailingValues = append(arg.TrailingValues, value)
+	} else {
+		arg.Value = value
+	}
+
+	return nil
+}
+
+// Return the group containing all commands whose full name starts with
+// fullName, creating it if necessary. Options added to a group are inherited
+// by all commands of the group.
+func (p *Program) AddCommandGroup(fullName, description string) *Command {
 	if p.Main != nil {
 		panic("cannot have a main function with commands")
 	}
 
 	names := splitCommandName(fullName)
 	if len(names) == 0 {
-		panic("empty command name")
+		panic("empty command group name")
 	}
 
+	group := p.commandGroup(names)
+	group.Description = description
+
+	return group
+}
+
+func (p *Program) commandGroup(names []string) *Command {
 	if p.command == nil {
-		p.command = p.newCommandGroup("", "")
+		p.command = p.newCommandGroup(nil, "", "")
 	}
 
 	group := p.command
 
-	for i := range len(names) - 1 {
-		name := names[i]
-
-		if group.subcommands == nil {
-			group.subcommands = make(map[string]*Command)
-		}
-
+	for i, name := range names {
+		// Groups have a subcommand map, even if they do not have any
+		// subcommand yet.
 		group2 := group.subcommands[name]
 		if group2 == nil {
-			group2 = p.newCommandGroup(name, strings.Join(names[:i+1], " "))
+			group2 = p.newCommandGroup(group, name,
+				strings.Join(names[:i+1], " "))
 			group.subcommands[name] = group2
+		} else if group2.subcommands == nil {
+			Panic("command %q cannot be used as a group", group2.FullName)
 		}
 
 		group = group2
 	}
 
+	return group
+}
+
+func (p *Program) AddCommand(fullName, description string, main Main) *Command {
+	if p.Main != nil {
+		panic("cannot have a main function with commands")
+	}
+
+	names := splitCommandName(fullName)
+	if len(names) == 0 {
+		panic("empty command name")
+	}
+
+	group := p.commandGroup(names[:len(names)-1])
+
 	name := names[len(names)-1]
 
 	cmd := Command{
@@ -108,28 +395,25 @@ func (p *Program) AddCommand(fullName, description string, main Main) *Command {
 		Main:        main,
 
 		program: p,
+		parent:  group,
 
 		options: make(map[string]*Option),
 	}
 
 	if cmd := group.subcommands[name]; cmd != nil {
-		if len(cmd.subcommands) == 0 {
+		if cmd.subcommands == nil {
 			Panic("duplicate command %q", cmd.FullName)
 		} else {
 			Panic("command %q has subcommands", cmd.FullName)
 		}
 	}
 
-	if group.Main != nil {
-		Panic("command %q cannot be used as a group", group.FullName)
-	}
-
 	group.subcommands[name] = &cmd
 
 	return &cmd
 }
 
-func (p *Program) AddOption(shortName, longName, valueName, defaultValue, description string) {
+func (p *Program) AddOption(shortName, longName, valueName, defaultValue, description string) *Option {
 	option := &Option{
 		ShortName:    shortName,
 		LongName:     longName,
@@ -139,13 +423,15 @@ func (p *Program) AddOption(shortName, longName, valueName, defaultValue, descri
 	}
 
 	p.addOption(nil, option)
+
+	return option
 }
 
-func (p *Program) AddFlag(shortName, longName, description string) {
-	p.AddOption(shortName, longName, "", "", description)
+func (p *Program) AddFlag(shortName, longName, description string) *Option {
+	return p.AddOption(shortName, longName, "", "", description)
 }
 
-func (c *Command) AddOption(shortName, longName, valueName, defaultValue, description string) {
+func (c *Command) AddOption(shortName, longName, valueName, defaultValue, description string) *Option {
 	option := &Option{
 		ShortName:    shortName,
 		LongName:     longName,
@@ -155,10 +441,12 @@ func (c *Command) AddOption(shortName, longName, valueName, defaultValue, descri
 	}
 
 	c.program.addOption(c, option)
+
+	return option
 }
 
-func (c *Command) AddFlag(shortName, longName, description string) {
-	c.AddOption(shortName, longName, "", "", description)
+func (c *Command) AddFlag(shortName, longName, description string) *Option {
+	return c.AddOption(shortName, longName, "", "", description)
 }
 
 func (p *Program) addOption(c *Command, option *Option) {
@@ -168,42 +456,66 @@ func (p *Program) addOption(c *Command, option *Option) {
 		panic("option has no short or long name")
 	}
 
+	if option.ShortName != "" && utf8.RuneCountInString(option.ShortName) != 1 {
+		Panic("invalid short option name %q: must be a single character",
+			option.ShortName)
+	}
+
+	option.Origin = OptionOrigin{Source: OptionSourceDefault}
+
+	option.index = p.optionCount
+	p.optionCount++
+
 	if c == nil {
 		m = p.options
 	} else {
 		m = c.options
 	}
 
-	if option.ShortName != "" {
-		if _, found := m[option.ShortName]; found {
-			Panic("duplicate option name %q", option.ShortName)
+	for _, name := range []string{option.ShortName, option.LongName} {
+		if name == "" {
+			continue
 		}
 
-		if c != nil {
-			if _, found := c.program.options[option.ShortName]; found {
-				Panic("duplicate option name %q", option.ShortName)
-			}
+		if p.isOptionNameUsed(c, name) {
+			Panic("duplicate option name %q", name)
 		}
 
-		m[option.ShortName] = option
+		m[name] = option
+	}
+}
+
+func (p *Program) isOptionNameUsed(c *Command, name string) bool {
+	// Option names must be unique among global options, options of the
+	// command, options of groups containing the command and, for a group,
+	// options of all commands it contains.
+	if _, found := p.options[name]; found {
+		return true
 	}
 
-	if option.LongName != "" {
-		if _, found := m[option.LongName]; found {
-			Panic("duplicate option name %q", option.LongName)
-		}
+	if c == nil {
+		return false
+	}
+
+	if _, found := c.allOptions()[name]; found {
+		return true
+	}
 
-		if c != nil {
-			if _, found := c.program.options[option.LongName]; found {
-				Panic("duplicate option name %q", option.LongName)
+	var fn func(*Command) bool
+	fn = func(c *Command) bool {
+		for _, subcmd := range c.subcommands {
+			if _, found := subcmd.options[name]; found || fn(subcmd) {
+				return true
 			}
 		}
 
-		m[option.LongName] = option
+		return false
 	}
+
+	return fn(c)
 }
 
-func (p *Program) AddArgument(name, description string) {
+func (p *Program) AddArgument(name, description string) *Argument {
 	checkForArgument(p.arguments)
 
 	arg := &Argument{
@@ -212,9 +524,11 @@ func (p *Program) AddArgument(name, description string) {
 	}
 
 	p.arguments = append(p.arguments, arg)
+
+	return arg
 }
 
-func (p *Program) AddOptionalArgument(name, description string) {
+func (p *Program) AddOptionalArgument(name, description string) *Argument {
 	checkForOptionalArgument(p.arguments)
 
 	arg := &Argument{
@@ -224,9 +538,11 @@ func (p *Program) AddOptionalArgument(name, description string) {
 	}
 
 	p.arguments = append(p.arguments, arg)
+
+	return arg
 }
 
-func (p *Program) AddTrailingArgument(name, description string) {
+func (p *Program) AddTrailingArgument(name, description string) *Argument {
 	checkForTrailingArgument(p.arguments)
 
 	arg := &Argument{
@@ -236,9 +552,11 @@ func (p *Program) AddTrailingArgument(name, description string) {
 	}
 
 	p.arguments = append(p.arguments, arg)
+
+	return arg
 }
 
-func (c *Command) AddArgument(name, description string) {
+func (c *Command) AddArgument(name, description string) *Argument {
 	checkForArgument(c.arguments)
 
 	arg := &Argument{
@@ -247,9 +565,11 @@ func (c *Command) AddArgument(name, description string) {
 	}
 
 	c.arguments = append(c.arguments, arg)
+
+	return arg
 }
 
-func (c *Command) AddOptionalArgument(name, description string) {
+func (c *Command) AddOptionalArgument(name, description string) *Argument {
 	checkForOptionalArgument(c.arguments)
 
 	arg := &Argument{
@@ -259,9 +579,11 @@ func (c *Command) AddOptionalArgument(name, description string) {
 	}
 
 	c.arguments = append(c.arguments, arg)
+
+	return arg
 }
 
-func (c *Command) AddTrailingArgument(name, description string) {
+func (c *Command) AddTrailingArgument(name, description string) *Argument {
 	checkForTrailingArgument(c.arguments)
 
 	arg := &Argument{
@@ -271,6 +593,8 @@ func (c *Command) AddTrailingArgument(name, description string) {
 	}
 
 	c.arguments = append(c.arguments, arg)
+
+	return arg
 }
 
 func checkForArgument(args []*Argument) {
@@ -342,20 +666,146 @@ func (p *Program) OptionValue(name string) string {
 	return opt.Value
 }
 
+func (p *Program) OptionValues(name string) []string {
+	opt := p.mustOption(name)
+	if !opt.Set {
+		if opt.DefaultValue == "" {
+			return nil
+		}
+
+		return []string{opt.DefaultValue}
+	}
+
+	return opt.Values
+}
+
+func (p *Program) OptionCount(name string) int {
+	return p.mustOption(name).Count
+}
+
 func (p *Program) BooleanOptionValue(name string) bool {
-	return p.booleanValue("option", name, p.OptionValue(name))
+	if opt := p.mustOption(name); !opt.takesValue() {
+		return opt.flagValue()
+	}
+
+	return typedValue(p, "option", name, p.OptionValue(name), parseBoolean)
+}
+
+// Return nil if the option was not set, or its boolean value, making it
+// possible to distinguish options explicitly set to false.
+func (p *Program) OptionalBooleanOptionValue(name string) *bool {
+	opt := p.mustOption(name)
+	if !opt.Set {
+		return nil
+	}
+
+	var value bool
+	if !opt.takesValue() {
+		value = opt.flagValue()
+	} else {
+		value = typedValue(p, "option", name, opt.Value, parseBoolean)
+	}
+
+	return &value
+}
+
+func (p *Program) IntegerOptionValue(name string) int64 {
+	return p.IntegerOptionValueInRange(name, math.MinInt64, math.MaxInt64)
+}
+
+func (p *Program) IntegerOptionValueInRange(name string, min, max int64) int64 {
+	return typedValue(p, "option", name, p.OptionValue(name),
+		func(s string) (int64, error) { return parseInteger(s, min, max) })
+}
+
+func (p *Program) FloatOptionValue(name string) float64 {
+	return typedValue(p, "option", name, p.OptionValue(name), parseFloat)
+}
+
+func (p *Program) DurationOptionValue(name string) time.Duration {
+	return typedValue(p, "option", name, p.OptionValue(name), parseDuration)
+}
+
+func (p *Program) ByteSizeOptionValue(name string) int64 {
+	return typedValue(p, "option", name, p.OptionValue(name), parseByteSize)
 }
 
 func (p *Program) RFC3339DatetimeOptionValue(name string) time.Time {
-	return p.rfc3339DatetimeValue("option", name, p.OptionValue(name))
+	return typedValue(p, "option", name, p.OptionValue(name),
+		parseRFC3339Datetime)
 }
 
 func (p *Program) UUIDOptionValue(name string) uuid.UUID {
-	return p.uuidValue("option", name, p.OptionValue(name))
+	return typedValue(p, "option", name, p.OptionValue(name), parseUUID)
 }
 
-func (p *Program) mustOption(name string) *Option {
+func (p *Program) URLOptionValue(name string) *url.URL {
+	return typedValue(p, "option", name, p.OptionValue(name), parseURL)
+}
+
+func (p *Program) IPAddressOptionValue(name string) netip.Addr {
+	return typedValue(p, "option", name, p.OptionValue(name), parseIPAddress)
+}
+
+func (p *Program) IPPrefixOptionValue(name string) netip.Prefix {
+	return typedValue(p, "option", name, p.OptionValue(name), parseIPPrefix)
+}
+
+func (p *Program) RegexpOptionValue(name string) *regexp.Regexp {
+	return typedValue(p, "option", name, p.OptionValue(name), parseRegexp)
+}
+
+func (p *Program) EnumOptionValue(name string, values ...string) string {
+	return typedValue(p, "option", name, p.OptionValue(name),
+		func(s string) (string, error) { return parseEnum(s, values) })
+}
+
+func (p *Program) selectedOptions() []*Option {
+	options := uniqueOptions(p.options)
+
 	if cmd := p.selectedCommand; cmd != nil {
+		options = append(options, uniqueOptions(cmd.allOptions())...)
+	}
+
+	return options
+}
+
+func uniqueOptions(m map[string]*Option) []*Option {
+	// Option maps contain options both by short and long name
+
+	var options []*Option
+
+	for name, opt := range m {
+		if name == opt.Name() {
+			options = append(options, opt)
+		}
+	}
+
+	slices.SortFunc(options, func(opt1, opt2 *Option) int {
+		return strings.Compare(opt1.sortKey(), opt2.sortKey())
+	})
+
+	return options
+}
+
+func optionSuggestions(name string, options map[string]*Option) []string {
+	var candidates []string
+
+	for _, opt := range uniqueOptions(options) {
+		if opt.LongName != "" && !opt.Hidden {
+			candidates = append(candidates, opt.LongName)
+		}
+
+		if opt.isNegatable() && !opt.Hidden {
+			candidates = append(candidates, "no-"+opt.LongName)
+		}
+	}
+
+	return suggestions(name, candidates)
+}
+
+func (p *Program) mustOption(name string) *Option {
+	for cmd := p.selectedCommand; cmd != nil; cmd = cmd.parent {
 		option, found := cmd.options[name]
 		if found {
 			return option
@@ -375,48 +825,67 @@ func (p *Program) ArgumentValue(name string) string {
 }
 
 func (p *Program) BooleanArgumentValue(name string) bool {
-	return p.booleanValue("argument", name, p.ArgumentValue(name))
+	return typedValue(p, "argument", name, p.ArgumentValue(name), parseBoolean)
+}
+
+func (p *Program) IntegerArgumentValue(name string) int64 {
+	return p.IntegerArgumentValueInRange(name, math.MinInt64, math.MaxInt64)
+}
+
+func (p *Program) IntegerArgumentValueInRange(name string, min, max int64) int64 {
+	return typedValue(p, "argument", name, p.ArgumentValue(name),
+		func(s string) (int64, error) { return parseInteger(s, min, max) })
+}
+
+func (p *Program) FloatArgumentValue(name string) float64 {
+	return typedValue(p, "argument", name, p.ArgumentValue(name), parseFloat)
+}
+
+func (p *Program) DurationArgumentValue(name string) time.Duration {
+	return typedValue(p, "argument", name, p.ArgumentValue(name), parseDuration)
+}
+
+func (p *Program) ByteSizeArgumentValue(name string) int64 {
+	return typedValue(p, "argument", name, p.ArgumentValue(name), parseByteSize)
 }
 
 func (p *Program) RFC3339DatetimeArgumentValue(name string) time.Time {
-	return p.rfc3339DatetimeValue("argument", name, p.OptionValue(name))
+	return typedValue(p, "argument", name, p.ArgumentValue(name),
+		parseRFC3339Datetime)
 }
 
 func (p *Program) UUIDArgumentValue(name string) uuid.UUID {
-	return p.uuidValue("argument", name, p.ArgumentValue(name))
+	return typedValue(p, "argument", name, p.ArgumentValue(name), parseUUID)
 }
 
-func (p *Program) booleanValue(typeName, name, value string) bool {
-	switch strings.ToLower(value) {
-	case "true":
-		return true
-	case "false":
-		return false
-	}
+func (p *Program) URLArgumentValue(name string) *url.URL {
+	return typedValue(p, "argument", name, p.ArgumentValue(name), parseURL)
+}
 
-	p.Fatal("invalid value %q for %s %q: must be either %q or %q",
-		value, typeName, name, "true", "false")
-	return false // make the Go compiler happy
+func (p *Program) IPAddressArgumentValue(name string) netip.Addr {
+	return typedValue(p, "argument", name, p.ArgumentValue(name), parseIPAddress)
 }
 
-func (p *Program) rfc3339DatetimeValue(typeName, name, value string) time.Time {
-	t, err := time.Parse(time.RFC3339Nano, value)
-	if err != nil {
-		p.Fatal("invalid value %q for %s %q: must be a valid RFC3339 datetime",
-			value, typeName, name)
-	}
+func (p *Program) IPPrefixArgumentValue(name string) netip.Prefix {
+	return typedValue(p, "argument", name, p.ArgumentValue(name), parseIPPrefix)
+}
 
-	return t
+func (p *Program) RegexpArgumentValue(name string) *regexp.Regexp {
+	return typedValue(p, "argument", name, p.ArgumentValue(name), parseRegexp)
 }
 
-func (p *Program) uuidValue(typeName, name, value string) uuid.UUID {
-	var id uuid.UUID
-	if err := id.Parse(value); err != nil {
-		p.Fatal("invalid value %q for %s %q: must be a valid UUID",
-			value, typeName, name)
+func (p *Program) EnumArgumentValue(name string, values ...string) string {
+	return typedValue(p, "argument", name, p.ArgumentValue(name),
+		func(s string) (string, error) { return parseEnum(s, values) })
+}
+
+func typedValue[T any](p *Program, typeName, name, value string, parse func(string) (T, error)) T {
+	v, err := parse(value)
+	if err != nil {
+		p.Fatal("invalid value %q for %s %q: %v", value, typeName, name, err)
 	}
 
-	return id
+	return v
 }
 
 func (p *Program) OptionalArgumentValue(name string) *string {
@@ -452,10 +921,14 @@ func (p *Program) mustArgument(name string) *Argument {
 }
 
 func (p *Program) findCommand(names []string) *Command {
+	if p.command == nil {
+		return nil
+	}
+
 	cmd := p.command
 
 	for _, name := range names {
-		cmd = cmd.subcommands[name]
+		cmd = cmd.findSubcommand(name)
 		if cmd == nil {
 			return nil
 		}
@@ -469,28 +942,204 @@ func (p *Program) findCommand(names []string) *Command {
 }
 
 func (p *Program) ParseCommandLine() {
-	if p.command != nil {
-		p.addDefaultCommands()
+	p.ParseCommandLineArgs(os.Args[1:])
+}
+
+func (p *Program) ParseCommandLineArgs(args []string) {
+	if len(args) > 0 && args[0] == completeArgument {
+		p.addDefaultCommandsOnce()
+		p.printCompletion(args[1:])
+		p.Exit(0)
+		return
 	}
 
-	p.parse()
+	if len(args) > 0 && args[0] == describeArgument {
+		if err := p.WriteDescription(p.Stdout); err != nil {
+			p.Fatal("%v", err)
+		}
+
+		p.Exit(0)
+		return
+	}
+
+	if err := p.ParseArgs(args); err != nil {
+		p.Fatal("%v", err)
+	}
 
 	if p.IsOptionSet("help") {
 		cmdHelp(p)
-		os.Exit(0)
+		p.Exit(0)
+	}
+
+	// Knowing where option values come from is useful when they can be set
+	// in multiple places.
+	if p.DebugLevel >= 2 {
+		p.printOptionOrigins()
+	}
+}
+
+func (p *Program) ParseArgs(args []string) error {
+	p.addDefaultCommandsOnce()
+
+	p.reset()
+
+	if err := p.parse(args); err != nil {
+		return err
+	}
+
+	if p.IsOptionSet("help") {
+		return nil
+	}
+
+	if err := p.applyEnvironment(); err != nil {
+		return err
+	}
+
+	if err := p.applyConfigurationFile(); err != nil {
+		return err
 	}
 
+	if err := p.checkDeprecations(); err != nil {
+		return err
+	}
+
+	if err := p.checkOptionConstraints(); err != nil {
+		return err
+	}
+
+	if err := p.parseDefaultValues(); err != nil {
+		return err
+	}
+
+	p.applyBindings()
+
 	p.Quiet = p.IsOptionSet("quiet")
 
 	if p.IsOptionSet("debug") {
 		s := p.OptionValue("debug")
 		i, err := strconv.ParseInt(s, 10, 64)
 		if err != nil || i < 0 || i > math.MaxInt32 {
-			p.Fatal("invalid debug level %q", s)
+			return &InvalidOptionValueError{
+				Option: "debug",
+				Value:  s,
+				Err:    fmt.Errorf("invalid debug level"),
+			}
 		}
 
 		p.DebugLevel = int(i)
 	}
+
+	return nil
+}
+
+func (p *Program) checkDeprecations() error {
+	// Deprecated options are reported wherever they were set, including in
+	// the environment and in configuration files.
+	var errs []error
+
+	if cmd := p.selectedCommand; cmd != nil && cmd.Deprecated != "" {
+		errs = append(errs, &DeprecatedCommandError{
+			Command: cmd.FullName,
+			Message: cmd.Deprecated,
+		})
+	}
+
+	for _, opt := range p.selectedOptions() {
+		if opt.Set && opt.Deprecated != "" {
+			errs = append(errs, &DeprecatedOptionError{
+				Option:  opt.Name(),
+				Message: opt.Deprecated,
+			})
+		}
+	}
+
+	for _, err := range errs {
+		if p.DeprecationErrors {
+			return err
+		}
+
+		p.Warning("%v", err)
+	}
+
+	return nil
+}
+
+func (p *Program) parseDefaultValues() error {
+	// Typed values must be initialized with the default value of their
+	// option when the option was not set on the command line, in the
+	// environment or in a configuration file.
+
+	for _, opt := range p.selectedOptions() {
+		if opt.Set || opt.TypedValue == nil || opt.DefaultValue == "" {
+			continue
+		}
+
+		if err := opt.TypedValue.Parse(opt.DefaultValue); err != nil {
+			return &InvalidOptionValueError{
+				Option: opt.Name(),
+				Value:  opt.DefaultValue,
+				Err:    err,
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *Program) reset() {
+	// Reset the state of all options and arguments so that the same program
+	// can be used to parse several command lines.
+
+	p.selectedCommand = nil
+
+	p.Quiet = false
+	p.DebugLevel = 0
+
+	resetOptions(p.options)
+	resetArguments(p.arguments)
+
+	p.resetBindings()
+
+	if p.command != nil {
+		p.command.reset()
+	}
+}
+
+func (c *Command) reset() {
+	resetOptions(c.options)
+	resetArguments(c.arguments)
+
+	for _, subcmd := range c.subcommands {
+		subcmd.reset()
+	}
+}
+
+func resetOptions(options map[string]*Option) {
+	for _, opt := range options {
+		opt.Set = false
+		opt.Value = ""
+		opt.Values = nil
+		opt.Count = 0
+		opt.Origin = OptionOrigin{Source: OptionSourceDefault}
+
+		resetValue(opt.TypedValue)
+	}
+}
+
+func resetArguments(args []*Argument) {
+	for _, arg := range args {
+		arg.Set = false
+		arg.Value = ""
+		arg.TrailingValues = nil
+
+		resetValue(arg.TypedValue)
+	}
+}
+
+func resetValue(value Value) {
+	if v, ok := value.(ResettableValue); ok {
+		v.Reset()
+	}
 }
 
 func (p *Program) addDefaultOptions() {
@@ -499,9 +1148,24 @@ func (p *Program) addDefaultOptions() {
 	p.AddOption("", "debug", "level", "0", "print debug messages")
 }
 
+func (p *Program) addDefaultCommandsOnce() {
+	if p.command != nil && !p.defaultCommandsAdded {
+		p.addDefaultCommands()
+		p.defaultCommandsAdded = true
+	}
+}
+
 func (p *Program) addDefaultCommands() {
 	c := p.AddCommand("help", "print help and exit", cmdHelp)
+	c.isDefault = true
 	c.AddTrailingArgument("command", "the name of the command")
+
+	c = p.AddCommand("completion", "print a shell completion script",
+		cmdCompletion)
+	c.isDefault = true
+	c.Hidden = true
+	c.AddArgument("shell", "the name of the shell").TypedValue =
+		&EnumValue{Values: completionShells}
 }
 
 func cmdHelp(p *Program) {
@@ -0,0 +1,428 @@
+package program
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+)
+
+// Completion is performed by the program itself: shell completion scripts
+// call the program with the hidden "__complete" argument followed by the
+// words of the command line, the last one being the word to complete. The
+// program prints one candidate per line, optionally followed by a tabulation
+// and a description, and a last line containing ':' followed by a hint
+// indicating whether the shell should also complete files or directories.
+
+const completeArgument = "__complete"
+
+var completionShells = []string{"bash", "fish", "zsh"}
+
+var shellIdentifierRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+type CompletionFunc func(p *Program, prefix string) []string
+
+type CompletionHint string
+
+const (
+	CompletionHintNone        CompletionHint = "none"
+	CompletionHintFiles       CompletionHint = "files"
+	CompletionHintDirectories CompletionHint = "directories"
+)
+
+type completionCandidate struct {
+	value       string
+	description string
+}
+
+func (p *Program) complete(words []string) ([]completionCandidate, CompletionHint) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+
+	p.reset()
+
+	prefix := words[len(words)-1]
+	words = words[:len(words)-1]
+
+	// Process previous words as the parser would do, ignoring errors, to
+	// find the current command, set options and count arguments.
+
+	cmd := p.command
+	options := maps.Clone(p.options)
+
+	var valueOption *Option
+	var endOfOptions bool
+	var nbArguments int
+
+	for _, word := range words {
+		if valueOption != nil {
+			valueOption.setValue(word, commandLineOrigin)
+			valueOption = nil
+			continue
+		}
+
+		if !endOfOptions && word == "--" {
+			endOfOptions = true
+			continue
+		}
+
+		if !endOfOptions && isLongOption(word) {
+			name, value, hasValue := strings.Cut(word[2:], "=")
+
+			if opt := options[name]; opt != nil {
+				if !opt.takesValue() {
+					opt.set(commandLineOrigin)
+				} else if hasValue {
+					opt.setValue(value, commandLineOrigin)
+				} else {
+					valueOption = opt
+				}
+			}
+
+			continue
+		}
+
+		if !endOfOptions && isShortOption(word) {
+			names := word[1:]
+
+			for i, c := range names {
+				opt := options[string(c)]
+				if opt == nil {
+					break
+				}
+
+				if !opt.takesValue() {
+					opt.set(commandLineOrigin)
+					continue
+				}
+
+				if rest := names[i+1:]; rest != "" {
+					opt.setValue(rest, commandLineOrigin)
+				} else {
+					valueOption = opt
+				}
+
+				break
+			}
+
+			continue
+		}
+
+		if cmd != nil && len(cmd.subcommands) > 0 && nbArguments == 0 {
+			if subcmd := cmd.findSubcommand(word); subcmd != nil {
+				cmd = subcmd
+				maps.Copy(options, cmd.options)
+				p.selectedCommand = cmd
+				continue
+			}
+		}
+
+		nbArguments++
+	}
+
+	if valueOption != nil {
+		return p.completeOptionValue(valueOption, prefix, "")
+	}
+
+	if !endOfOptions && strings.HasPrefix(prefix, "-") {
+		if name, value, found := strings.Cut(prefix, "="); found {
+			opt := options[strings.TrimLeft(name, "-")]
+			if opt == nil || !opt.takesValue() {
+				return nil, CompletionHintNone
+			}
+
+			return p.completeOptionValue(opt, value, name+"=")
+		}
+
+		return completeOptionNames(options, prefix), CompletionHintNone
+	}
+
+	if cmd != nil && len(cmd.subcommands) > 0 {
+		if nbArguments > 0 {
+			return nil, CompletionHintNone
+		}
+
+		return completeCommandNames(cmd, prefix), CompletionHintNone
+	}
+
+	var arguments []*Argument
+	if cmd == nil {
+		arguments = p.arguments
+	} else {
+		arguments = cmd.arguments
+	}
+
+	if len(arguments) == 0 {
+		return nil, CompletionHintNone
+	}
+
+	var arg *Argument
+	if nbArguments < len(arguments) {
+		arg = arguments[nbArguments]
+	} else if lastArg := arguments[len(arguments)-1]; lastArg.Trailing {
+		arg = lastArg
+	} else {
+		return nil, CompletionHintNone
+	}
+
+	return p.completeValue(arg.Complete, arg.CompletionHint, arg.TypedValue,
+		prefix, "")
+}
+
+func (p *Program) completeOptionValue(opt *Option, prefix, valuePrefix string) ([]completionCandidate, CompletionHint) {
+	return p.completeValue(opt.Complete, opt.CompletionHint, opt.TypedValue,
+		prefix, valuePrefix)
+}
+
+func (p *Program) completeValue(fn CompletionFunc, hint CompletionHint, typedValue Value, prefix, valuePrefix string) ([]completionCandidate, CompletionHint) {
+	var values []string
+
+	if fn != nil {
+		values = fn(p, prefix)
+	} else if enum, ok := typedValue.(*EnumValue); ok {
+		values = enum.Values
+	} else if hint == "" {
+		// Without any information about the value, the best we can do is to
+		// let the shell complete file names.
+		hint = CompletionHintFiles
+	}
+
+	if hint == "" {
+		hint = CompletionHintNone
+	}
+
+	var candidates []completionCandidate
+
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			candidate := completionCandidate{value: valuePrefix + value}
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	return candidates, hint
+}
+
+func completeOptionNames(options map[string]*Option, prefix string) []completionCandidate {
+	var candidates []completionCandidate
+
+	for _, opt := range uniqueOptions(options) {
+		if opt.Hidden {
+			continue
+		}
+
+		var names []string
+
+		if opt.ShortName != "" {
+			names = append(names, "-"+opt.ShortName)
+		}
+
+		if opt.LongName != "" {
+			names = append(names, "--"+opt.LongName)
+		}
+
+		if opt.isNegatable() {
+			names = append(names, "--no-"+opt.LongName)
+		}
+
+		for _, name := range names {
+			if strings.HasPrefix(name, prefix) {
+				candidate := completionCandidate{
+					value:       name,
+					description: opt.Description,
+				}
+
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	return candidates
+}
+
+func completeCommandNames(cmd *Command, prefix string) []completionCandidate {
+	var candidates []completionCandidate
+
+	names := maps.Keys(cmd.subcommands)
+	slices.Sort(names)
+
+	for _, name := range names {
+		subcmd := cmd.subcommands[name]
+		if subcmd.Hidden || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		candidate := completionCandidate{
+			value:       name,
+			description: subcmd.Description,
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates
+}
+
+func (p *Program) printCompletion(words []string) {
+	var buf bytes.Buffer
+
+	candidates, hint := p.complete(words)
+
+	for _, candidate := range candidates {
+		buf.WriteString(candidate.value)
+
+		if candidate.description != "" {
+			description := strings.ReplaceAll(candidate.description, "\n", " ")
+			fmt.Fprintf(&buf, "\t%s", description)
+		}
+
+		buf.WriteByte('\n')
+	}
+
+	fmt.Fprintf(&buf, ":%s\n", hint)
+
+	io.Copy(p.Stdout, &buf)
+}
+
+func (p *Program) WriteCompletionScript(w io.Writer, shell string) error {
+	var buf bytes.Buffer
+
+	switch shell {
+	case "bash":
+		p.writeBashCompletionScript(&buf)
+	case "fish":
+		p.writeFishCompletionScript(&buf)
+	case "zsh":
+		p.writeZshCompletionScript(&buf)
+	default:
+		return fmt.Errorf("unsupported shell %q", shell)
+	}
+
+	_, err := io.Copy(w, &buf)
+	return err
+}
+
+func (p *Program) completionFunctionName() string {
+	return "_" + shellIdentifierRE.ReplaceAllString(p.Name, "_")
+}
+
+func (p *Program) writeBashCompletionScript(buf *bytes.Buffer) {
+	fnName := p.completionFunctionName()
+
+	fmt.Fprintf(buf, "# bash completion for %s\n", p.Name)
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "%s() {\n", fnName)
+	fmt.Fprintf(buf, "    local cur line hint\n")
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "    cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	fmt.Fprintf(buf, "    COMPREPLY=()\n")
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "    while IFS= read -r line; do\n")
+	fmt.Fprintf(buf, "        case \"$line\" in\n")
+	fmt.Fprintf(buf, "            :*) hint=\"${line#:}\" ;;\n")
+	fmt.Fprintf(buf, "            *) COMPREPLY+=(\"${line%%%%$'\\t'*}\") ;;\n")
+	fmt.Fprintf(buf, "        esac\n")
+	fmt.Fprintf(buf, "    done < <(\"${COMP_WORDS[0]}\" %s \"${COMP_WORDS[@]:1:COMP_CWORD}\" 2>/dev/null)\n",
+		completeArgument)
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "    case \"$hint\" in\n")
+	fmt.Fprintf(buf, "        %s)\n", CompletionHintFiles)
+	fmt.Fprintf(buf, "            compopt -o filenames 2>/dev/null\n")
+	fmt.Fprintf(buf, "            mapfile -t -O \"${#COMPREPLY[@]}\" COMPREPLY < <(compgen -f -- \"$cur\")\n")
+	fmt.Fprintf(buf, "            ;;\n")
+	fmt.Fprintf(buf, "        %s)\n", CompletionHintDirectories)
+	fmt.Fprintf(buf, "            compopt -o filenames 2>/dev/null\n")
+	fmt.Fprintf(buf, "            mapfile -t -O \"${#COMPREPLY[@]}\" COMPREPLY < <(compgen -d -- \"$cur\")\n")
+	fmt.Fprintf(buf, "            ;;\n")
+	fmt.Fprintf(buf, "    esac\n")
+	fmt.Fprintf(buf, "}\n")
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "complete -F %s %s\n", fnName, p.Name)
+}
+
+func (p *Program) writeZshCompletionScript(buf *bytes.Buffer) {
+	fnName := p.completionFunctionName()
+
+	fmt.Fprintf(buf, "#compdef %s\n", p.Name)
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "%s() {\n", fnName)
+	fmt.Fprintf(buf, "    local line value hint\n")
+	fmt.Fprintf(buf, "    local -a candidates\n")
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "    while IFS= read -r line; do\n")
+	fmt.Fprintf(buf, "        case \"$line\" in\n")
+	fmt.Fprintf(buf, "            :*)\n")
+	fmt.Fprintf(buf, "                hint=\"${line#:}\"\n")
+	fmt.Fprintf(buf, "                ;;\n")
+	fmt.Fprintf(buf, "            *$'\\t'*)\n")
+	fmt.Fprintf(buf, "                value=\"${line%%%%$'\\t'*}\"\n")
+	fmt.Fprintf(buf, "                candidates+=(\"${value//:/\\\\:}:${line#*$'\\t'}\")\n")
+	fmt.Fprintf(buf, "                ;;\n")
+	fmt.Fprintf(buf, "            *)\n")
+	fmt.Fprintf(buf, "                candidates+=(\"${line//:/\\\\:}\")\n")
+	fmt.Fprintf(buf, "                ;;\n")
+	fmt.Fprintf(buf, "        esac\n")
+	fmt.Fprintf(buf, "    done < <(\"${words[1]}\" %s \"${(@)words[2,CURRENT]}\" 2>/dev/null)\n",
+		completeArgument)
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "    if ((${#candidates} > 0)); then\n")
+	fmt.Fprintf(buf, "        _describe 'values' candidates\n")
+	fmt.Fprintf(buf, "    fi\n")
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "    case \"$hint\" in\n")
+	fmt.Fprintf(buf, "        %s) _files ;;\n", CompletionHintFiles)
+	fmt.Fprintf(buf, "        %s) _files -/ ;;\n", CompletionHintDirectories)
+	fmt.Fprintf(buf, "    esac\n")
+	fmt.Fprintf(buf, "}\n")
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "if [[ \"${funcstack[1]}\" == %s ]]; then\n", fnName)
+	fmt.Fprintf(buf, "    %s \"$@\"\n", fnName)
+	fmt.Fprintf(buf, "else\n")
+	fmt.Fprintf(buf, "    compdef %s %s\n", fnName, p.Name)
+	fmt.Fprintf(buf, "fi\n")
+}
+
+func (p *Program) writeFishCompletionScript(buf *bytes.Buffer) {
+	fnName := p.completionFunctionName()
+
+	fmt.Fprintf(buf, "# fish completion for %s\n", p.Name)
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "function %s\n", fnName)
+	fmt.Fprintf(buf, "    set -l tokens (commandline -opc)\n")
+	fmt.Fprintf(buf, "    set -l program $tokens[1]\n")
+	fmt.Fprintf(buf, "    set -e tokens[1]\n")
+	fmt.Fprintf(buf, "    set -l current (commandline -ct)\n")
+	fmt.Fprintf(buf, "    set -l hint\n")
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "    for line in ($program %s $tokens \"$current\" 2>/dev/null)\n",
+		completeArgument)
+	fmt.Fprintf(buf, "        if string match -q -- ':*' $line\n")
+	fmt.Fprintf(buf, "            set hint (string sub -s 2 -- $line)\n")
+	fmt.Fprintf(buf, "        else\n")
+	fmt.Fprintf(buf, "            echo $line\n")
+	fmt.Fprintf(buf, "        end\n")
+	fmt.Fprintf(buf, "    end\n")
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "    switch \"$hint\"\n")
+	fmt.Fprintf(buf, "        case %s\n", CompletionHintFiles)
+	fmt.Fprintf(buf, "            __fish_complete_path \"$current\"\n")
+	fmt.Fprintf(buf, "        case %s\n", CompletionHintDirectories)
+	fmt.Fprintf(buf, "            __fish_complete_directories \"$current\"\n")
+	fmt.Fprintf(buf, "    end\n")
+	fmt.Fprintf(buf, "end\n")
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "complete -c %s -f -a '(%s)'\n", p.Name, fnName)
+}
+
+func cmdCompletion(p *Program) {
+	shell := p.ArgumentValue("shell")
+
+	if err := p.WriteCompletionScript(p.Stdout, shell); err != nil {
+		p.Fatal("cannot write completion script: %v", err)
+	}
+}
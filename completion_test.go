@@ -0,0 +1,190 @@
+package program
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComplete(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewProgram("test", "")
+	p.AddOption("c", "", "value", "", "")
+	p.AddOption("", "format", "format", "", "").TypedValue =
+		&EnumValue{Values: []string{"json", "text"}}
+
+	c := p.AddCommand("foo create", "create a foo", func(p *Program) {})
+	c.AddFlag("n", "dry-run", "")
+	c.AddOption("o", "output", "path", "", "").CompletionHint =
+		CompletionHintDirectories
+	c.AddArgument("type", "").Complete =
+		func(p *Program, prefix string) []string {
+			if p.IsOptionSet("dry-run") {
+				return []string{"test"}
+			}
+
+			return []string{"aaa", "aab", "abc"}
+		}
+	c.AddTrailingArgument("file", "")
+
+	p.AddCommand("foo delete", "delete a foo", func(p *Program) {})
+	p.AddCommand("bar", "", func(p *Program) {})
+
+	p.addDefaultCommandsOnce()
+
+	tests := []struct {
+		words      []string
+		candidates []string
+		hint       CompletionHint
+	}{
+		{[]string{""},
+			[]string{"bar", "foo", "help"}, CompletionHintNone},
+		{[]string{"f"},
+			[]string{"foo"}, CompletionHintNone},
+		{[]string{"foo", ""},
+			[]string{"create", "delete"}, CompletionHintNone},
+		{[]string{"-c", "x", "foo", ""},
+			[]string{"create", "delete"}, CompletionHintNone},
+		{[]string{"-c", ""},
+			nil, CompletionHintFiles},
+		{[]string{"--format", ""},
+			[]string{"json", "text"}, CompletionHintNone},
+		{[]string{"--format=t"},
+			[]string{"--format=text"}, CompletionHintNone},
+		{[]string{"foo", "create", "--d"},
+			[]string{"--debug", "--dry-run"}, CompletionHintNone},
+		{[]string{"foo", "create", "-o", ""},
+			nil, CompletionHintDirectories},
+		{[]string{"foo", "create", "a"},
+			[]string{"aaa", "aab", "abc"}, CompletionHintNone},
+		{[]string{"foo", "create", "-n", ""},
+			[]string{"test"}, CompletionHintNone},
+		{[]string{"foo", "create", "aaa", ""},
+			nil, CompletionHintFiles},
+		{[]string{"foo", "create", "aaa", "x", ""},
+			nil, CompletionHintFiles},
+		{[]string{"completion"},
+			nil, CompletionHintNone},
+		{[]string{"completion", ""},
+			[]string{"bash", "fish", "zsh"}, CompletionHintNone},
+	}
+
+	for _, test := range tests {
+		label := fmt.Sprintf("%q", test.words)
+
+		candidates, hint := p.complete(test.words)
+
+		var values []string
+		for _, candidate := range candidates {
+			values = append(values, candidate.value)
+		}
+
+		assert.Equal(test.candidates, values, label)
+		assert.Equal(test.hint, hint, label)
+	}
+}
+
+func newBashCompletionTestProgram() *Program {
+	p := NewProgram("test", "")
+	p.AddOption("c", "", "value", "", "")
+
+	c := p.AddCommand("foo create", "", func(p *Program) {})
+	c.AddFlag("n", "dry-run", "")
+	c.AddOption("o", "output", "path", "", "").CompletionHint =
+		CompletionHintDirectories
+
+	p.AddCommand("foo delete", "", func(p *Program) {})
+	p.AddCommand("bar", "", func(p *Program) {})
+
+	return p
+}
+
+// Executed as the program called by the completion script in
+// TestBashCompletion.
+func TestBashCompletionProgram(t *testing.T) {
+	if os.Getenv("PROGRAM_COMPLETION_TEST") == "" {
+		t.Skip("only run by TestBashCompletion")
+	}
+
+	var args []string
+	for i, arg := range os.Args {
+		if arg == "--" {
+			args = os.Args[i+1:]
+			break
+		}
+	}
+
+	newBashCompletionTestProgram().ParseCommandLineArgs(args)
+	os.Exit(0)
+}
+
+func TestBashCompletion(t *testing.T) {
+	assert := assert.New(t)
+
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+
+	// The completion script calls the program with the "__complete"
+	// argument; the program is the test binary itself running
+	// TestBashCompletionProgram.
+	dirPath := t.TempDir()
+
+	programPath := filepath.Join(dirPath, "test")
+	programScript := fmt.Sprintf("#!/bin/sh\n"+
+		"PROGRAM_COMPLETION_TEST=1 exec %s "+
+		"-test.run='^TestBashCompletionProgram$' -- \"$@\"\n",
+		bashQuote(os.Args[0]))
+
+	err := os.WriteFile(programPath, []byte(programScript), 0755)
+	require.NoError(t, err)
+
+	subdirPath := filepath.Join(dirPath, "subdir")
+	require.NoError(t, os.Mkdir(subdirPath, 0755))
+
+	var script bytes.Buffer
+	p := newBashCompletionTestProgram()
+	require.NoError(t, p.WriteCompletionScript(&script, "bash"))
+
+	tests := []struct {
+		words       []string
+		completions string
+	}{
+		{[]string{""}, "bar foo help"},
+		{[]string{"f"}, "foo"},
+		{[]string{"foo", ""}, "create delete"},
+		{[]string{"-c", "x", "foo", ""}, "create delete"},
+		{[]string{"foo", "create", "--d"}, "--debug --dry-run"},
+		{[]string{"foo", "create", "-o", dirPath + "/s"}, subdirPath},
+		{[]string{"completion", ""}, "bash fish zsh"},
+	}
+
+	for _, test := range tests {
+		words := []string{bashQuote(programPath)}
+		for _, word := range test.words {
+			words = append(words, bashQuote(word))
+		}
+
+		code := fmt.Sprintf("%s\nCOMP_WORDS=(%s)\nCOMP_CWORD=%d\n"+
+			"_test\necho \"${COMPREPLY[*]}\"\n",
+			script.String(), strings.Join(words, " "), len(test.words))
+
+		output, err := exec.Command("bash", "-c", code).Output()
+		if assert.NoError(err, test.words) {
+			assert.Equal(test.completions,
+				strings.TrimSpace(string(output)), test.words)
+		}
+	}
+}
+
+func bashQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
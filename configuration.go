@@ -0,0 +1,387 @@
+package program
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/maps"
+)
+
+// Configuration files contain values for options. Entries at the top of the
+// file apply to program options; entries in a section apply to the options of
+// the command whose full name is the name of the section (or to program
+// options when the command is selected).
+//
+// Two formats are supported. Files whose extension is ".json" contain a JSON
+// object; other files use a simple INI format:
+//
+//     # Comment
+//     debug = 1
+//
+//     [foo create]
+//     dry-run = true
+//     label = "a b"
+//     label = c
+//
+// Repeatable options are set with multiple entries, or with JSON arrays.
+// Values of configuration files have a lower priority than those set on the
+// command line or with environment variables.
+
+var configurationFileNames = []string{"config.ini", "config.json"}
+
+type configuration struct {
+	path     string
+	sections map[string][]configurationEntry
+}
+
+type configurationEntry struct {
+	key   string
+	value string
+	line  int
+}
+
+func (p *Program) EnableConfigurationFiles() {
+	p.AddOption("", "config", "path", "",
+		"the path of the configuration file")
+
+	p.configurationFilesEnabled = true
+}
+
+func (p *Program) ConfigurationFileSearchPath() []string {
+	dirPath, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+
+	var filePaths []string
+	for _, name := range configurationFileNames {
+		filePaths = append(filePaths, filepath.Join(dirPath, p.Name, name))
+	}
+
+	return filePaths
+}
+
+func (p *Program) findConfigurationFile() (string, error) {
+	if p.IsOptionSet("config") {
+		return p.OptionValue("config"), nil
+	}
+
+	for _, filePath := range p.ConfigurationFileSearchPath() {
+		_, err := os.Stat(filePath)
+		if err == nil {
+			return filePath, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("cannot stat %q: %w", filePath, err)
+		}
+	}
+
+	return "", nil
+}
+
+func (p *Program) applyConfigurationFile() error {
+	if !p.configurationFilesEnabled {
+		return nil
+	}
+
+	filePath, err := p.findConfigurationFile()
+	if err != nil {
+		return err
+	} else if filePath == "" {
+		return nil
+	}
+
+	cfg, err := loadConfiguration(filePath)
+	if err != nil {
+		return err
+	}
+
+	// Options set on the command line or in the environment are ignored, but
+	// all entries of an option which was not must be applied, including
+	// repeated entries.
+	setOptions := make(map[*Option]bool)
+	for _, opt := range p.selectedOptions() {
+		setOptions[opt] = opt.Set
+	}
+
+	// Sections must use the full name of commands, not aliases or prefixes.
+	for name := range cfg.sections {
+		if name == "" {
+			continue
+		}
+
+		names := splitCommandName(name)
+		if cmd := p.findCommand(names); cmd == nil ||
+			cmd.FullName != strings.Join(names, " ") {
+			return &ConfigurationFileError{
+				Path: cfg.path,
+				Err:  fmt.Errorf("unknown command %q", name),
+			}
+		}
+	}
+
+	// Command sections are more specific than group sections, which are
+	// more specific than the top-level section; they are applied first.
+	cmd := p.selectedCommand
+	for cmd != nil && cmd.FullName != "" {
+		entries := cfg.sections[cmd.FullName]
+
+		err := p.applyConfigurationEntries(cfg, entries, cmd.allOptions(),
+			setOptions)
+		if err != nil {
+			return err
+		}
+
+		cmd = cmd.parent
+	}
+
+	entries := cfg.sections[""]
+	return p.applyConfigurationEntries(cfg, entries, nil, setOptions)
+}
+
+func (p *Program) applyConfigurationEntries(cfg *configuration, entries []configurationEntry, cmdOptions map[string]*Option, setOptions map[*Option]bool) error {
+	appliedOptions := make(map[*Option]bool)
+
+	for _, entry := range entries {
+		opt, found := cmdOptions[entry.key]
+		if !found {
+			opt, found = p.options[entry.key]
+		}
+
+		if !found {
+			return &ConfigurationFileError{
+				Path: cfg.path,
+				Line: entry.line,
+				Err:  &UnknownOptionError{Option: entry.key},
+			}
+		}
+
+		// Setting the help option in a configuration file would prevent the
+		// program from running, and the path of the configuration file
+		// cannot be set in the file itself.
+		if opt == p.options["help"] || opt == p.options["config"] {
+			return &ConfigurationFileError{
+				Path: cfg.path,
+				Line: entry.line,
+				Err: fmt.Errorf("option %q cannot be set in a configuration "+
+					"file", entry.key),
+			}
+		}
+
+		if setOptions[opt] {
+			continue
+		}
+
+		if err := p.applyConfigurationEntry(cfg, opt, entry); err != nil {
+			return &ConfigurationFileError{
+				Path: cfg.path,
+				Line: entry.line,
+				Err: &InvalidOptionValueError{
+					Option: entry.key,
+					Value:  entry.value,
+					Err:    err,
+				},
+			}
+		}
+
+		appliedOptions[opt] = true
+	}
+
+	maps.Copy(setOptions, appliedOptions)
+
+	return nil
+}
+
+func (p *Program) applyConfigurationEntry(cfg *configuration, opt *Option, entry configurationEntry) error {
+	origin := OptionOrigin{
+		Source:   OptionSourceFile,
+		FilePath: cfg.path,
+		Line:     entry.line,
+	}
+
+	if opt.takesValue() {
+		return opt.setValue(entry.value, origin)
+	}
+
+	set, err := parseBoolean(entry.value)
+	if err != nil {
+		return err
+	}
+
+	if set || opt.isNegatable() {
+		opt.setFlag(set, origin)
+	}
+
+	return nil
+}
+
+func loadConfiguration(filePath string) (*configuration, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %q: %w", filePath, err)
+	}
+
+	cfg := configuration{
+		path:     filePath,
+		sections: make(map[string][]configurationEntry),
+	}
+
+	if filepath.Ext(filePath) == ".json" {
+		err = cfg.parseJSON(data)
+	} else {
+		err = cfg.parseINI(data)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+func (cfg *configuration) parseINI(data []byte) error {
+	section := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return &ConfigurationFileError{
+					Path: cfg.path,
+					Line: lineNumber,
+					Err:  fmt.Errorf("invalid section header"),
+				}
+			}
+
+			names := splitCommandName(line[1 : len(line)-1])
+			section = strings.Join(names, " ")
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return &ConfigurationFileError{
+				Path: cfg.path,
+				Line: lineNumber,
+				Err:  fmt.Errorf("invalid entry: missing '='"),
+			}
+		}
+
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		if strings.HasPrefix(value, "\"") {
+			s, err := strconv.Unquote(value)
+			if err != nil {
+				return &ConfigurationFileError{
+					Path: cfg.path,
+					Line: lineNumber,
+					Err:  fmt.Errorf("invalid quoted string"),
+				}
+			}
+
+			value = s
+		}
+
+		entry := configurationEntry{
+			key:   key,
+			value: value,
+			line:  lineNumber,
+		}
+
+		cfg.sections[section] = append(cfg.sections[section], entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("cannot read %q: %w", cfg.path, err)
+	}
+
+	return nil
+}
+
+func (cfg *configuration) parseJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var obj map[string]any
+	if err := decoder.Decode(&obj); err != nil {
+		return &ConfigurationFileError{
+			Path: cfg.path,
+			Err:  fmt.Errorf("invalid JSON object: %w", err),
+		}
+	}
+
+	keys := maps.Keys(obj)
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		section, isSection := obj[key].(map[string]any)
+		if !isSection {
+			if err := cfg.addJSONEntries("", key, obj[key]); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		name := strings.Join(splitCommandName(key), " ")
+
+		keys2 := maps.Keys(section)
+		slices.Sort(keys2)
+
+		for _, key2 := range keys2 {
+			if err := cfg.addJSONEntries(name, key2, section[key2]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (cfg *configuration) addJSONEntries(section, key string, value any) error {
+	values, isArray := value.([]any)
+	if !isArray {
+		values = []any{value}
+	}
+
+	for _, value := range values {
+		var s string
+
+		switch v := value.(type) {
+		case string:
+			s = v
+		case json.Number:
+			s = v.String()
+		case bool:
+			s = strconv.FormatBool(v)
+		default:
+			return &ConfigurationFileError{
+				Path: cfg.path,
+				Err:  fmt.Errorf("invalid value for entry %q", key),
+			}
+		}
+
+		entry := configurationEntry{key: key, value: s}
+		cfg.sections[section] = append(cfg.sections[section], entry)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package program
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigurationFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	dirPath := t.TempDir()
+
+	writeFile := func(name, content string) string {
+		filePath := filepath.Join(dirPath, name)
+		err := os.WriteFile(filePath, []byte(content), 0644)
+		require.NoError(t, err)
+		return filePath
+	}
+
+	p := NewProgram("test", "")
+	p.EnvironmentVariablePrefix = "TEST_"
+	p.EnableConfigurationFiles()
+	p.AddOption("o", "output", "path", "-", "")
+	p.AddOption("l", "level", "level", "info", "")
+
+	c := p.AddCommand("foo create", "", func(p *Program) {})
+	c.AddFlag("n", "dry-run", "")
+	c.AddOption("L", "label", "name", "", "").Repeatable = true
+
+	iniFilePath := writeFile("config.ini", `
+# Global options
+output = "out.txt"
+level = debug
+
+[foo   create]
+dry-run = true
+label = a
+label = "b c"
+level = error
+`)
+
+	jsonFilePath := writeFile("config.json", `{
+  "output": "out.txt",
+  "level": "debug",
+  "foo create": {
+    "dry-run": true,
+    "label": ["a", "b c"],
+    "level": "error"
+  }
+}`)
+
+	for _, filePath := range []string{iniFilePath, jsonFilePath} {
+		t.Setenv("TEST_OUTPUT", "")
+
+		args := []string{"--config", filePath, "foo", "create"}
+		if assert.NoError(p.ParseArgs(args), filePath) {
+			assert.Equal("out.txt", p.OptionValue("output"), filePath)
+			assert.Equal("error", p.OptionValue("level"), filePath)
+			assert.True(p.IsOptionSet("dry-run"), filePath)
+			assert.Equal([]string{"a", "b c"}, p.OptionValues("label"),
+				filePath)
+		}
+
+		t.Setenv("TEST_OUTPUT", "env.txt")
+
+		args = []string{"--config", filePath, "foo", "create", "-L", "d"}
+		if assert.NoError(p.ParseArgs(args), filePath) {
+			assert.Equal("env.txt", p.OptionValue("output"), filePath)
+			assert.Equal([]string{"d"}, p.OptionValues("label"), filePath)
+		}
+	}
+
+	filePath := writeFile("invalid.ini", "output = foo\nfoo = bar\n")
+	err := p.ParseArgs([]string{"--config", filePath, "foo", "create"})
+	assert.EqualError(err, filePath+`:2: unknown option "foo"`)
+
+	filePath = writeFile("invalid.json", `{"bar": {"level": "info"}}`)
+	err = p.ParseArgs([]string{"--config", filePath, "foo", "create"})
+	assert.EqualError(err, filePath+`: unknown command "bar"`)
+
+	filePath = writeFile("help.ini", "help = true\n")
+	err = p.ParseArgs([]string{"--config", filePath, "foo", "create"})
+	assert.EqualError(err, filePath+
+		`:1: option "help" cannot be set in a configuration file`)
+
+	filePath = writeFile("nested.json", `{"config": "other.json"}`)
+	err = p.ParseArgs([]string{"--config", filePath, "foo", "create"})
+	assert.EqualError(err, filePath+
+		`: option "config" cannot be set in a configuration file`)
+}
+
+func TestConfigurationFileSectionsWithoutCommands(t *testing.T) {
+	assert := assert.New(t)
+
+	dirPath := t.TempDir()
+
+	p := NewProgram("test", "")
+	p.EnableConfigurationFiles()
+	p.AddOption("o", "output", "path", "-", "")
+
+	files := map[string]string{
+		"config.ini":  "output = out.txt\n[foo]\noutput = foo.txt\n",
+		"config.json": `{"output": "out.txt", "foo": {"output": "foo.txt"}}`,
+	}
+
+	for name, content := range files {
+		filePath := filepath.Join(dirPath, name)
+		err := os.WriteFile(filePath, []byte(content), 0644)
+		require.NoError(t, err)
+
+		err = p.ParseArgs([]string{"--config", filePath})
+
+		var cfgErr *ConfigurationFileError
+		if assert.ErrorAs(err, &cfgErr, name) {
+			assert.Equal(filePath, cfgErr.Path, name)
+			assert.EqualError(cfgErr.Err, `unknown command "foo"`, name)
+		}
+	}
+}
+
+func TestOptionOrigins(t *testing.T) {
+	assert := assert.New(t)
+
+	filePath := filepath.Join(t.TempDir(), "config.ini")
+	err := os.WriteFile(filePath, []byte("\nc = foo\n"), 0644)
+	require.NoError(t, err)
+
+	p := NewProgram("test", "")
+	p.EnvironmentVariablePrefix = "TEST_"
+	p.EnableConfigurationFiles()
+	p.AddOption("a", "option-a", "value", "", "")
+	p.AddOption("b", "option-b", "value", "", "")
+	p.AddOption("c", "", "value", "", "")
+	p.AddOption("d", "", "value", "", "")
+
+	t.Setenv("TEST_OPTION_B", "foo")
+
+	args := []string{"--config", filePath, "-a", "foo"}
+	if assert.NoError(p.ParseArgs(args)) {
+		assert.Equal(OptionOrigin{Source: OptionSourceCommandLine},
+			p.OptionOrigin("option-a"))
+		assert.Equal(OptionOrigin{Source: OptionSourceEnvironment,
+			EnvironmentVariable: "TEST_OPTION_B"},
+			p.OptionOrigin("option-b"))
+		assert.Equal(OptionOrigin{Source: OptionSourceFile,
+			FilePath: filePath, Line: 2},
+			p.OptionOrigin("c"))
+		assert.Equal(OptionOrigin{Source: OptionSourceDefault},
+			p.OptionOrigin("d"))
+
+		assert.Equal("file "+filePath+":2", p.OptionOrigin("c").String())
+	}
+}
@@ -0,0 +1,238 @@
+package program
+
+import (
+	"slices"
+	"strings"
+)
+
+type optionConstraintType string
+
+const (
+	// At most one option of the group can be set.
+	optionConstraintExclusive optionConstraintType = "exclusive"
+
+	// At least one option of the group must be set.
+	optionConstraintRequired optionConstraintType = "required"
+
+	// If the first option is set, all other options must be set.
+	optionConstraintDependency optionConstraintType = "dependency"
+)
+
+type optionConstraint struct {
+	Type    optionConstraintType
+	Options []*Option
+}
+
+// Declare a group of options which cannot be used together. Options must be
+// declared before the group.
+func (p *Program) AddExclusiveOptionGroup(names ...string) {
+	p.optionConstraints = append(p.optionConstraints,
+		newOptionConstraint(optionConstraintExclusive, names, p.options, nil))
+}
+
+// Declare a group of options of which at least one must be set. Options must
+// be declared before the group.
+func (p *Program) AddRequiredOptionGroup(names ...string) {
+	p.optionConstraints = append(p.optionConstraints,
+		newOptionConstraint(optionConstraintRequired, names, p.options, nil))
+}
+
+// Declare that if an option is set, other options must also be set. Options
+// must be declared before the dependency.
+func (p *Program) AddOptionDependency(name string, requiredNames ...string) {
+	names := append([]string{name}, requiredNames...)
+
+	p.optionConstraints = append(p.optionConstraints,
+		newOptionConstraint(optionConstraintDependency, names, p.options, nil))
+}
+
+func (c *Command) AddExclusiveOptionGroup(names ...string) {
+	c.optionConstraints = append(c.optionConstraints,
+		newOptionConstraint(optionConstraintExclusive, names, c.allOptions(),
+			c.program.options))
+}
+
+func (c *Command) AddRequiredOptionGroup(names ...string) {
+	c.optionConstraints = append(c.optionConstraints,
+		newOptionConstraint(optionConstraintRequired, names, c.allOptions(),
+			c.program.options))
+}
+
+func (c *Command) AddOptionDependency(name string, requiredNames ...string) {
+	names := append([]string{name}, requiredNames...)
+
+	c.optionConstraints = append(c.optionConstraints,
+		newOptionConstraint(optionConstraintDependency, names, c.allOptions(),
+			c.program.options))
+}
+
+func (c *Command) allOptionConstraints() []*optionConstraint {
+	// Constraints of groups apply to all commands they contain
+	var constraints []*optionConstraint
+
+	for c2 := c; c2 != nil; c2 = c2.parent {
+		constraints = append(constraints, c2.optionConstraints...)
+	}
+
+	return constraints
+}
+
+func newOptionConstraint(cType optionConstraintType, names []string, options, globalOptions map[string]*Option) *optionConstraint {
+	if len(names) < 2 {
+		panic("option constraints must contain at least two options")
+	}
+
+	c := optionConstraint{Type: cType}
+
+	for _, name := range names {
+		opt, found := options[name]
+		if !found {
+			opt, found = globalOptions[name]
+		}
+
+		if !found {
+			Panic("unknown option %q", name)
+		}
+
+		c.Options = append(c.Options, opt)
+	}
+
+	return &c
+}
+
+func (c *optionConstraint) optionNames() []string {
+	names := make([]string, len(c.Options))
+	for i, opt := range c.Options {
+		names[i] = opt.Name()
+	}
+
+	return names
+}
+
+func (c *optionConstraint) isRequiredVariant(c2 *optionConstraint) bool {
+	if c2.Type != optionConstraintRequired || len(c2.Options) != len(c.Options) {
+		return false
+	}
+
+	for _, opt := range c.Options {
+		if !slices.Contains(c2.Options, opt) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *optionConstraint) usageString() string {
+	strs := make([]string, len(c.Options))
+	for i, opt := range c.Options {
+		strs[i] = opt.usageString()
+	}
+
+	s := strings.Join(strs, " | ")
+
+	if c.Type == optionConstraintRequired {
+		return "(" + s + ")"
+	}
+
+	return "[" + s + "]"
+}
+
+func (p *Program) checkOptionConstraints() error {
+	if cmd := p.selectedCommand; cmd != nil && cmd.isDefault {
+		return nil
+	}
+
+	options := p.selectedOptions()
+
+	constraints := p.optionConstraints
+	if cmd := p.selectedCommand; cmd != nil {
+		constraints = append(slices.Clip(constraints),
+			cmd.allOptionConstraints()...)
+	}
+
+	for _, opt := range options {
+		if opt.Required && !opt.Set {
+			return &MissingOptionError{Option: opt.Name()}
+		}
+	}
+
+	for _, c := range constraints {
+		var setOptions []string
+		for _, opt := range c.Options {
+			if opt.Set {
+				setOptions = append(setOptions, opt.Name())
+			}
+		}
+
+		switch c.Type {
+		case optionConstraintExclusive:
+			if len(setOptions) > 1 {
+				return &ConflictingOptionsError{Options: setOptions}
+			}
+
+		case optionConstraintRequired:
+			if len(setOptions) == 0 {
+				return &MissingOptionGroupError{Options: c.optionNames()}
+			}
+
+		case optionConstraintDependency:
+			if !c.Options[0].Set {
+				continue
+			}
+
+			for _, opt := range c.Options[1:] {
+				if !opt.Set {
+					return &MissingRequiredOptionError{
+						Option:         c.Options[0].Name(),
+						RequiredOption: opt.Name(),
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *Program) optionConstraintsUsageString(options map[string]*Option, constraints []*optionConstraint) string {
+	var strs []string
+
+	for _, opt := range uniqueOptions(options) {
+		if opt.Required && !opt.Hidden {
+			strs = append(strs, opt.usageString())
+		}
+	}
+
+	for _, c := range constraints {
+		if c.Type == optionConstraintDependency {
+			continue
+		}
+
+		// A group which is both exclusive and required is displayed once:
+		// exactly one option must be set.
+		if c.Type == optionConstraintExclusive &&
+			slices.ContainsFunc(constraints, c.isRequiredVariant) {
+			continue
+		}
+
+		strs = append(strs, c.usageString())
+	}
+
+	return strings.Join(strs, " ")
+}
+
+func (opt *Option) usageString() string {
+	var s string
+	if opt.LongName != "" {
+		s = "--" + opt.LongName
+	} else {
+		s = "-" + opt.ShortName
+	}
+
+	if opt.takesValue() {
+		s += " <" + opt.valueName() + ">"
+	}
+
+	return s
+}
@@ -0,0 +1,194 @@
+package program
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"golang.org/x/exp/maps"
+)
+
+// Calling the program with the hidden "__describe" argument prints the JSON
+// description of the command line interface on the standard output.
+const describeArgument = "__describe"
+
+// The description of a program is meant to be stable: commands and options
+// are sorted and fields are never renamed, so that descriptions of different
+// versions of a program can be compared.
+
+type ProgramDescription struct {
+	Name         string                    `json:"name"`
+	Description  string                    `json:"description,omitempty"`
+	Options      []*OptionDescription      `json:"options,omitempty"`
+	OptionGroups []*OptionGroupDescription `json:"option_groups,omitempty"`
+	Arguments    []*ArgumentDescription    `json:"arguments,omitempty"`
+	Commands     []*CommandDescription     `json:"commands,omitempty"`
+}
+
+type CommandDescription struct {
+	Name         string                    `json:"name"`
+	FullName     string                    `json:"full_name"`
+	Description  string                    `json:"description,omitempty"`
+	Hidden       bool                      `json:"hidden,omitempty"`
+	Aliases      []string                  `json:"aliases,omitempty"`
+	Deprecated   string                    `json:"deprecated,omitempty"`
+	Options      []*OptionDescription      `json:"options,omitempty"`
+	OptionGroups []*OptionGroupDescription `json:"option_groups,omitempty"`
+	Arguments    []*ArgumentDescription    `json:"arguments,omitempty"`
+	Subcommands  []*CommandDescription     `json:"subcommands,omitempty"`
+}
+
+type OptionDescription struct {
+	ShortName           string `json:"short_name,omitempty"`
+	LongName            string `json:"long_name,omitempty"`
+	ValueName           string `json:"value_name,omitempty"`
+	Type                string `json:"type,omitempty"`
+	DefaultValue        string `json:"default_value,omitempty"`
+	Description         string `json:"description,omitempty"`
+	EnvironmentVariable string `json:"environment_variable,omitempty"`
+	Repeatable          bool   `json:"repeatable,omitempty"`
+	Negatable           bool   `json:"negatable,omitempty"`
+	Group               string `json:"group,omitempty"`
+	Hidden              bool   `json:"hidden,omitempty"`
+	Deprecated          string `json:"deprecated,omitempty"`
+	Required            bool   `json:"required,omitempty"`
+}
+
+// Groups are either "exclusive" (at most one option can be set), "required"
+// (at least one option must be set) or "dependency" (if the first option is
+// set, all other options must be set).
+type OptionGroupDescription struct {
+	Type    string   `json:"type"`
+	Options []string `json:"options"`
+}
+
+type ArgumentDescription struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Optional    bool   `json:"optional,omitempty"`
+	Trailing    bool   `json:"trailing,omitempty"`
+}
+
+func (p *Program) Describe() *ProgramDescription {
+	p.addDefaultCommandsOnce()
+
+	pd := ProgramDescription{
+		Name:         p.Name,
+		Description:  p.Description,
+		Options:      p.describeOptions(p.options),
+		OptionGroups: describeOptionGroups(p.optionConstraints),
+		Arguments:    describeArguments(p.arguments),
+	}
+
+	if p.command != nil {
+		pd.Commands = p.describeCommands(p.command)
+	}
+
+	return &pd
+}
+
+func (p *Program) WriteDescription(w io.Writer) error {
+	data, err := json.MarshalIndent(p.Describe(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode description: %w", err)
+	}
+
+	data = append(data, '\n')
+
+	_, err = w.Write(data)
+	return err
+}
+
+func (p *Program) describeCommands(cmd *Command) []*CommandDescription {
+	names := maps.Keys(cmd.subcommands)
+	slices.Sort(names)
+
+	cds := make([]*CommandDescription, len(names))
+
+	for i, name := range names {
+		subcmd := cmd.subcommands[name]
+
+		cds[i] = &CommandDescription{
+			Name:         subcmd.Name,
+			FullName:     subcmd.FullName,
+			Description:  subcmd.Description,
+			Hidden:       subcmd.Hidden,
+			Aliases:      subcmd.Aliases,
+			Deprecated:   subcmd.Deprecated,
+			Options:      p.describeOptions(subcmd.options),
+			OptionGroups: describeOptionGroups(subcmd.optionConstraints),
+			Arguments:    describeArguments(subcmd.arguments),
+			Subcommands:  p.describeCommands(subcmd),
+		}
+	}
+
+	return cds
+}
+
+func (p *Program) describeOptions(options map[string]*Option) []*OptionDescription {
+	var ods []*OptionDescription
+
+	for _, opt := range uniqueOptions(options) {
+		od := OptionDescription{
+			ShortName:           opt.ShortName,
+			LongName:            opt.LongName,
+			DefaultValue:        opt.DefaultValue,
+			Description:         opt.Description,
+			EnvironmentVariable: p.optionEnvironmentVariable(opt),
+			Repeatable:          opt.Repeatable,
+			Negatable:           opt.isNegatable(),
+			Group:               opt.Group,
+			Hidden:              opt.Hidden,
+			Deprecated:          opt.Deprecated,
+			Required:            opt.Required,
+		}
+
+		if opt.takesValue() {
+			od.ValueName = opt.valueName()
+		}
+
+		if opt.TypedValue != nil {
+			od.Type = opt.TypedValue.TypeName()
+		}
+
+		ods = append(ods, &od)
+	}
+
+	return ods
+}
+
+func describeOptionGroups(constraints []*optionConstraint) []*OptionGroupDescription {
+	var ogds []*OptionGroupDescription
+
+	for _, c := range constraints {
+		ogds = append(ogds, &OptionGroupDescription{
+			Type:    string(c.Type),
+			Options: c.optionNames(),
+		})
+	}
+
+	return ogds
+}
+
+func describeArguments(args []*Argument) []*ArgumentDescription {
+	var ads []*ArgumentDescription
+
+	for _, arg := range args {
+		ad := ArgumentDescription{
+			Name:        arg.Name,
+			Description: arg.Description,
+			Optional:    arg.Optional,
+			Trailing:    arg.Trailing,
+		}
+
+		if arg.TypedValue != nil {
+			ad.Type = arg.TypedValue.TypeName()
+		}
+
+		ads = append(ads, &ad)
+	}
+
+	return ads
+}
@@ -0,0 +1,106 @@
+package program
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteDescription(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewProgram("test", "a test program")
+	p.EnvironmentVariablePrefix = "TEST_"
+
+	create := p.AddCommand("foo create", "create a foo", func(*Program) {})
+	create.AddOption("n", "count", "", "1", "the number of foos").
+		TypedValue = &IntegerValue{}
+	create.AddTrailingArgument("name", "the names of the foos")
+
+	var buf bytes.Buffer
+	if !assert.NoError(p.WriteDescription(&buf)) {
+		return
+	}
+
+	assert.JSONEq(`{
+  "name": "test",
+  "description": "a test program",
+  "options": [
+    {
+      "long_name": "debug",
+      "value_name": "level",
+      "default_value": "0",
+      "description": "print debug messages",
+      "environment_variable": "TEST_DEBUG"
+    },
+    {
+      "short_name": "h",
+      "long_name": "help",
+      "description": "print help and exit"
+    },
+    {
+      "short_name": "q",
+      "long_name": "quiet",
+      "description": "do not print status and information messages",
+      "environment_variable": "TEST_QUIET"
+    }
+  ],
+  "commands": [
+    {
+      "name": "completion",
+      "full_name": "completion",
+      "description": "print a shell completion script",
+      "hidden": true,
+      "arguments": [
+        {
+          "name": "shell",
+          "type": "bash|fish|zsh",
+          "description": "the name of the shell"
+        }
+      ]
+    },
+    {
+      "name": "foo",
+      "full_name": "foo",
+      "subcommands": [
+        {
+          "name": "create",
+          "full_name": "foo create",
+          "description": "create a foo",
+          "options": [
+            {
+              "short_name": "n",
+              "long_name": "count",
+              "value_name": "integer",
+              "type": "integer",
+              "default_value": "1",
+              "description": "the number of foos",
+              "environment_variable": "TEST_COUNT"
+            }
+          ],
+          "arguments": [
+            {
+              "name": "name",
+              "description": "the names of the foos",
+              "trailing": true
+            }
+          ]
+        }
+      ]
+    },
+    {
+      "name": "help",
+      "full_name": "help",
+      "description": "print help and exit",
+      "arguments": [
+        {
+          "name": "command",
+          "description": "the name of the command",
+          "trailing": true
+        }
+      ]
+    }
+  ]
+}`, buf.String())
+}
@@ -0,0 +1,296 @@
+package program
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+)
+
+type DocumentationFormat string
+
+const (
+	DocumentationFormatMarkdown DocumentationFormat = "markdown"
+	DocumentationFormatHTML     DocumentationFormat = "html"
+)
+
+func (f DocumentationFormat) fileExtension() string {
+	switch f {
+	case DocumentationFormatMarkdown:
+		return "md"
+	case DocumentationFormatHTML:
+		return "html"
+	default:
+		panic(fmt.Sprintf("unknown documentation format %q", f))
+	}
+}
+
+// Write one documentation page for the program and for each visible command
+// in a directory. Pages are named after the program and the full name of the
+// command, e.g. "foo-bar-create.md", and are linked to each other.
+func (p *Program) WriteDocumentation(dirPath string, format DocumentationFormat) error {
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return fmt.Errorf("cannot create directory %q: %w", dirPath, err)
+	}
+
+	ext := format.fileExtension()
+
+	for _, cmd := range append([]*Command{nil}, p.documentedCommands()...) {
+		var buf bytes.Buffer
+
+		if err := p.WriteDocumentationPage(&buf, cmd, format); err != nil {
+			return err
+		}
+
+		filePath := filepath.Join(dirPath, p.pageName(cmd)+"."+ext)
+
+		if err := os.WriteFile(filePath, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("cannot write %q: %w", filePath, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Program) WriteDocumentationPage(w io.Writer, cmd *Command, format DocumentationFormat) error {
+	if p.command != nil {
+		p.addDefaultCommandsOnce()
+
+		if cmd == nil {
+			cmd = p.command
+		}
+	}
+
+	u := p.usage(cmd)
+	ext := format.fileExtension()
+
+	var buf bytes.Buffer
+
+	switch format {
+	case DocumentationFormatMarkdown:
+		p.writeMarkdownPage(&buf, u, ext)
+	case DocumentationFormatHTML:
+		p.writeHTMLPage(&buf, u, ext)
+	}
+
+	_, err := io.Copy(w, &buf)
+	return err
+}
+
+func (p *Program) writeMarkdownPage(buf *bytes.Buffer, u *usage, ext string) {
+	fmt.Fprintf(buf, "# %s\n\n", markdownEscape(u.Name))
+
+	fmt.Fprintf(buf, "```\n%s\n```\n", u.Line)
+
+	if u.Description != "" {
+		fmt.Fprintf(buf, "\n%s\n", markdownEscape(sentence(u.Description)))
+	}
+
+	for _, paragraph := range u.LongDescription {
+		fmt.Fprintf(buf, "\n%s\n", markdownEscape(paragraph))
+	}
+
+	if len(u.Commands) > 0 {
+		fmt.Fprintf(buf, "\n## %s\n\n", sectionTitle(u.CommandsLabel))
+
+		for _, c := range u.Commands {
+			fmt.Fprintf(buf, "- [`%s`](%s.%s)", c.Label,
+				p.pageName(c.Command), ext)
+
+			if c.Description != "" {
+				fmt.Fprintf(buf, ": %s", markdownEscape(c.Description))
+			}
+
+			buf.WriteByte('\n')
+		}
+	}
+
+	writeEntries := func(label string, entries []usageEntry) {
+		fmt.Fprintf(buf, "\n## %s\n\n", sectionTitle(label))
+
+		for _, entry := range entries {
+			fmt.Fprintf(buf, "- `%s`", strings.TrimSpace(entry.Label))
+
+			if entry.Description != "" {
+				fmt.Fprintf(buf, ": %s", markdownEscape(entry.Description))
+			}
+
+			buf.WriteByte('\n')
+		}
+	}
+
+	if len(u.Arguments) > 0 {
+		writeEntries("ARGUMENTS", u.Arguments)
+	}
+
+	for _, section := range u.OptionSections {
+		writeEntries(section.Label, section.entries())
+	}
+
+	if len(u.Examples) > 0 {
+		fmt.Fprintf(buf, "\n## Examples\n")
+
+		for _, example := range u.Examples {
+			fmt.Fprintf(buf, "\n```\n%s\n```\n", example.Label)
+
+			if example.Description != "" {
+				fmt.Fprintf(buf, "\n%s\n", markdownEscape(example.Description))
+			}
+		}
+	}
+
+	if len(u.SeeAlso) > 0 {
+		fmt.Fprintf(buf, "\n## See also\n\n")
+
+		for _, ref := range u.SeeAlso {
+			if ref.Command == nil {
+				fmt.Fprintf(buf, "- `%s`\n", ref.Name)
+			} else {
+				fmt.Fprintf(buf, "- [`%s`](%s.%s)\n", ref.Name,
+					p.pageName(ref.Command), ext)
+			}
+		}
+	}
+}
+
+func (p *Program) writeHTMLPage(buf *bytes.Buffer, u *usage, ext string) {
+	e := html.EscapeString
+
+	fmt.Fprintf(buf, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; }
+pre, code { font-family: monospace; }
+pre { padding: 0.5em; background: #f4f4f4; }
+dd { margin-bottom: 0.5em; }
+</style>
+</head>
+<body>
+`, e(u.Name))
+
+	fmt.Fprintf(buf, "<h1>%s</h1>\n", e(u.Name))
+	fmt.Fprintf(buf, "<pre>%s</pre>\n", e(u.Line))
+
+	if u.Description != "" {
+		fmt.Fprintf(buf, "<p>%s</p>\n", e(sentence(u.Description)))
+	}
+
+	for _, paragraph := range u.LongDescription {
+		fmt.Fprintf(buf, "<p>%s</p>\n", e(paragraph))
+	}
+
+	if len(u.Commands) > 0 {
+		fmt.Fprintf(buf, "<h2>%s</h2>\n<dl>\n", e(sectionTitle(u.CommandsLabel)))
+
+		for _, c := range u.Commands {
+			fmt.Fprintf(buf, "<dt><a href=\"%s.%s\"><code>%s</code></a></dt>\n",
+				e(p.pageName(c.Command)), ext, e(c.Label))
+			fmt.Fprintf(buf, "<dd>%s</dd>\n", e(c.Description))
+		}
+
+		fmt.Fprintf(buf, "</dl>\n")
+	}
+
+	writeEntries := func(label string, entries []usageEntry) {
+		fmt.Fprintf(buf, "<h2>%s</h2>\n<dl>\n", e(sectionTitle(label)))
+
+		for _, entry := range entries {
+			fmt.Fprintf(buf, "<dt><code>%s</code></dt>\n",
+				e(strings.TrimSpace(entry.Label)))
+			fmt.Fprintf(buf, "<dd>%s</dd>\n", e(entry.Description))
+		}
+
+		fmt.Fprintf(buf, "</dl>\n")
+	}
+
+	if len(u.Arguments) > 0 {
+		writeEntries("ARGUMENTS", u.Arguments)
+	}
+
+	for _, section := range u.OptionSections {
+		writeEntries(section.Label, section.entries())
+	}
+
+	if len(u.Examples) > 0 {
+		fmt.Fprintf(buf, "<h2>Examples</h2>\n")
+
+		for _, example := range u.Examples {
+			fmt.Fprintf(buf, "<pre>%s</pre>\n", e(example.Label))
+
+			if example.Description != "" {
+				fmt.Fprintf(buf, "<p>%s</p>\n", e(example.Description))
+			}
+		}
+	}
+
+	if len(u.SeeAlso) > 0 {
+		fmt.Fprintf(buf, "<h2>See also</h2>\n<ul>\n")
+
+		for _, ref := range u.SeeAlso {
+			if ref.Command == nil {
+				fmt.Fprintf(buf, "<li><code>%s</code></li>\n", e(ref.Name))
+			} else {
+				fmt.Fprintf(buf, "<li><a href=\"%s.%s\"><code>%s</code></a></li>\n",
+					e(p.pageName(ref.Command)), ext, e(ref.Name))
+			}
+		}
+
+		fmt.Fprintf(buf, "</ul>\n")
+	}
+
+	fmt.Fprintf(buf, "</body>\n</html>\n")
+}
+
+func (p *Program) documentedCommands() []*Command {
+	// All visible commands including command groups, sorted by full name.
+
+	if p.command == nil {
+		return nil
+	}
+
+	p.addDefaultCommandsOnce()
+
+	var cmds []*Command
+
+	var fn func(*Command)
+	fn = func(cmd *Command) {
+		names := maps.Keys(cmd.subcommands)
+		slices.Sort(names)
+
+		for _, name := range names {
+			subcmd := cmd.subcommands[name]
+			if subcmd.Hidden {
+				continue
+			}
+
+			cmds = append(cmds, subcmd)
+			fn(subcmd)
+		}
+	}
+
+	fn(p.command)
+
+	return cmds
+}
+
+func sectionTitle(label string) string {
+	// "GLOBAL OPTIONS" -> "Global options"
+	return label[:1] + strings.ToLower(label[1:])
+}
+
+var markdownReplacer = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`)
+
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(s)
+}
@@ -0,0 +1,54 @@
+package program
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteDocumentationPage(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewProgram("test", "a test program")
+	p.AddFlag("", "dry-run", "do not *modify* anything")
+
+	create := p.AddCommand("foo create", "create a foo", nil)
+	create.AddOption("n", "name", "name", "", "the name of the foo")
+	create.AddArgument("path", "the path of the foo")
+	create.SeeAlso = []string{"help", "old"}
+
+	p.AddCommand("old", "", nil).Hidden = true
+
+	var buf bytes.Buffer
+
+	// Program page
+	err := p.WriteDocumentationPage(&buf, nil, DocumentationFormatMarkdown)
+	if assert.NoError(err) {
+		assert.Contains(buf.String(), "# test\n\n"+
+			"```\ntest [GLOBAL OPTIONS] COMMAND...\n```\n\n"+
+			"A test program.\n")
+		assert.Contains(buf.String(), "## Commands\n\n"+
+			"- [`foo <subcommand>...`](test-foo.md)\n"+
+			"- [`help`](test-help.md): print help and exit\n")
+		assert.Contains(buf.String(),
+			"- `--dry-run`: do not \\*modify\\* anything\n")
+	}
+
+	// Command page
+	buf.Reset()
+
+	err = p.WriteDocumentationPage(&buf, create, DocumentationFormatHTML)
+	if assert.NoError(err) {
+		assert.Contains(buf.String(), "<h1>test foo create</h1>\n"+
+			"<pre>test [GLOBAL OPTIONS] foo create [COMMAND OPTIONS] "+
+			"&lt;path&gt;</pre>\n")
+		assert.Contains(buf.String(), "<h2>Command options</h2>\n<dl>\n"+
+			"<dt><code>-n, --name &lt;name&gt;</code></dt>\n"+
+			"<dd>the name of the foo</dd>\n</dl>\n")
+		assert.Contains(buf.String(), "<h2>See also</h2>\n<ul>\n"+
+			"<li><a href=\"test-help.html\"><code>test help</code></a></li>\n"+
+			"<li><code>test old</code></li>\n"+
+			"</ul>\n")
+	}
+}
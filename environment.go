@@ -4,6 +4,7 @@ import (
 	"cmp"
 	"fmt"
 	"os"
+	"strings"
 
 	"go.n16f.net/uuid"
 )
@@ -48,3 +49,67 @@ func UUIDEnvironmentVariable(name string) uuid.UUID {
 
 	return id
 }
+
+func (p *Program) optionEnvironmentVariable(opt *Option) string {
+	if opt.EnvironmentVariable != "" {
+		return opt.EnvironmentVariable
+	}
+
+	// Setting the help option with an environment variable would prevent
+	// the program from running.
+	if p.EnvironmentVariablePrefix == "" || opt.LongName == "" ||
+		opt.LongName == "help" {
+		return ""
+	}
+
+	name := strings.ToUpper(strings.ReplaceAll(opt.LongName, "-", "_"))
+	return p.EnvironmentVariablePrefix + name
+}
+
+func (p *Program) applyEnvironment() error {
+	for _, opt := range p.selectedOptions() {
+		if opt.Set {
+			continue
+		}
+
+		name := p.optionEnvironmentVariable(opt)
+		if name == "" {
+			continue
+		}
+
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+
+		origin := OptionOrigin{
+			Source:              OptionSourceEnvironment,
+			EnvironmentVariable: name,
+		}
+
+		if opt.takesValue() {
+			if err := opt.setValue(value, origin); err != nil {
+				return &InvalidEnvironmentVariableError{
+					Variable: name,
+					Value:    value,
+					Err:      err,
+				}
+			}
+		} else {
+			set, err := parseBoolean(value)
+			if err != nil {
+				return &InvalidEnvironmentVariableError{
+					Variable: name,
+					Value:    value,
+					Err:      err,
+				}
+			}
+
+			if set || opt.isNegatable() {
+				opt.setFlag(set, origin)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package program
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionEnvironmentVariables(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewProgram("test", "")
+	p.EnvironmentVariablePrefix = "TEST_"
+	p.AddFlag("n", "dry-run", "")
+	p.AddOption("o", "output", "path", "-", "")
+	p.AddOption("c", "", "value", "", "").EnvironmentVariable = "TEST_VALUE"
+
+	t.Setenv("TEST_DRY_RUN", "true")
+	t.Setenv("TEST_OUTPUT", "foo.txt")
+	t.Setenv("TEST_VALUE", "bar")
+	t.Setenv("TEST_HELP", "true")
+
+	if assert.NoError(p.ParseArgs([]string{})) {
+		assert.False(p.IsOptionSet("help"))
+		assert.True(p.IsOptionSet("dry-run"))
+		assert.Equal("foo.txt", p.OptionValue("output"))
+		assert.Equal("bar", p.OptionValue("c"))
+	}
+
+	if assert.NoError(p.ParseArgs([]string{"-o", "bar.txt", "-cbaz"})) {
+		assert.Equal("bar.txt", p.OptionValue("output"))
+		assert.Equal("baz", p.OptionValue("c"))
+	}
+
+	t.Setenv("TEST_DRY_RUN", "false")
+	t.Setenv("TEST_OUTPUT", "")
+
+	if assert.NoError(p.ParseArgs([]string{})) {
+		assert.False(p.IsOptionSet("dry-run"))
+		assert.Equal("-", p.OptionValue("output"))
+	}
+
+	t.Setenv("TEST_DRY_RUN", "yes")
+
+	err := p.ParseArgs([]string{})
+	assert.EqualError(err, `invalid value "yes" for environment variable `+
+		`"TEST_DRY_RUN": must be either "true" or "false"`)
+}
@@ -0,0 +1,223 @@
+package program
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type UnknownOptionError struct {
+	Option      string
+	Suggestions []string
+}
+
+func (err *UnknownOptionError) Error() string {
+	msg := fmt.Sprintf("unknown option %q", err.Option)
+
+	if len(err.Suggestions) > 0 {
+		names := make([]string, len(err.Suggestions))
+		for i, name := range err.Suggestions {
+			names[i] = "--" + name
+		}
+
+		msg += "; " + didYouMean(names)
+	}
+
+	return msg
+}
+
+type MissingOptionValueError struct {
+	Option string
+}
+
+func (err *MissingOptionValueError) Error() string {
+	return fmt.Sprintf("missing value for option %q", err.Option)
+}
+
+type MissingOptionError struct {
+	Option string
+}
+
+func (err *MissingOptionError) Error() string {
+	return fmt.Sprintf("missing required option %q", err.Option)
+}
+
+type ConflictingOptionsError struct {
+	Options []string
+}
+
+func (err *ConflictingOptionsError) Error() string {
+	return fmt.Sprintf("options %s cannot be used together",
+		quotedList(err.Options, "and"))
+}
+
+type MissingOptionGroupError struct {
+	Options []string
+}
+
+func (err *MissingOptionGroupError) Error() string {
+	return fmt.Sprintf("missing option: one of %s must be set",
+		quotedList(err.Options, "or"))
+}
+
+type MissingRequiredOptionError struct {
+	Option         string
+	RequiredOption string
+}
+
+func (err *MissingRequiredOptionError) Error() string {
+	return fmt.Sprintf("option %q requires option %q", err.Option,
+		err.RequiredOption)
+}
+
+type UnexpectedOptionValueError struct {
+	Option string
+}
+
+func (err *UnexpectedOptionValueError) Error() string {
+	return fmt.Sprintf("option %q does not take a value", err.Option)
+}
+
+type InvalidOptionValueError struct {
+	Option string
+	Value  string
+	Err    error
+}
+
+func (err *InvalidOptionValueError) Error() string {
+	return fmt.Sprintf("invalid value %q for option %q: %v",
+		err.Value, err.Option, err.Err)
+}
+
+func (err *InvalidOptionValueError) Unwrap() error {
+	return err.Err
+}
+
+type InvalidArgumentValueError struct {
+	Argument string
+	Value    string
+	Err      error
+}
+
+func (err *InvalidArgumentValueError) Error() string {
+	return fmt.Sprintf("invalid value %q for argument %q: %v",
+		err.Value, err.Argument, err.Err)
+}
+
+func (err *InvalidArgumentValueError) Unwrap() error {
+	return err.Err
+}
+
+type InvalidEnvironmentVariableError struct {
+	Variable string
+	Value    string
+	Err      error
+}
+
+func (err *InvalidEnvironmentVariableError) Error() string {
+	return fmt.Sprintf("invalid value %q for environment variable %q: %v",
+		err.Value, err.Variable, err.Err)
+}
+
+func (err *InvalidEnvironmentVariableError) Unwrap() error {
+	return err.Err
+}
+
+type ConfigurationFileError struct {
+	Path string
+	Line int
+	Err  error
+}
+
+func (err *ConfigurationFileError) Error() string {
+	if err.Line == 0 {
+		return fmt.Sprintf("%s: %v", err.Path, err.Err)
+	}
+
+	return fmt.Sprintf("%s:%d: %v", err.Path, err.Line, err.Err)
+}
+
+func (err *ConfigurationFileError) Unwrap() error {
+	return err.Err
+}
+
+type MissingArgumentError struct {
+	Argument string
+}
+
+func (err *MissingArgumentError) Error() string {
+	return fmt.Sprintf("missing argument %q", err.Argument)
+}
+
+type TooManyArgumentsError struct {
+	Arguments []string
+}
+
+func (err *TooManyArgumentsError) Error() string {
+	return "too many arguments"
+}
+
+type MissingCommandError struct {
+	Command string
+}
+
+func (err *MissingCommandError) Error() string {
+	if err.Command == "" {
+		return "missing command"
+	}
+
+	return fmt.Sprintf("missing subcommand(s) for command %q", err.Command)
+}
+
+type UnknownCommandError struct {
+	Command     []string
+	Suggestions []string
+}
+
+func (err *UnknownCommandError) Error() string {
+	msg := fmt.Sprintf("unknown command %q", strings.Join(err.Command, " "))
+
+	if len(err.Suggestions) > 0 {
+		msg += "; " + didYouMean(err.Suggestions)
+	}
+
+	return msg
+}
+
+func didYouMean(names []string) string {
+	return "did you mean " + quotedList(names, "or") + "?"
+}
+
+func quotedList(names []string, conjunction string) string {
+	quotedNames := make([]string, len(names))
+	for i, name := range names {
+		quotedNames[i] = strconv.Quote(name)
+	}
+
+	if len(names) == 1 {
+		return quotedNames[0]
+	}
+
+	last := len(quotedNames) - 1
+
+	return strings.Join(quotedNames[:last], ", ") + " " + conjunction + " " +
+		quotedNames[last]
+}
+
+type DeprecatedCommandError struct {
+	Command string
+	Message string
+}
+
+func (err *DeprecatedCommandError) Error() string {
+	return fmt.Sprintf("command %q is deprecated: %s", err.Command, err.Message)
+}
+
+type DeprecatedOptionError struct {
+	Option  string
+	Message string
+}
+
+func (err *DeprecatedOptionError) Error() string {
+	return fmt.Sprintf("option %q is deprecated: %s", err.Option, err.Message)
+}
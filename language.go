@@ -1,6 +1,8 @@
 package program
 
 import (
+	"slices"
+	"strings"
 	"unicode"
 )
 
@@ -18,3 +20,100 @@ func sentence(s string) string {
 
 	return string(runes)
 }
+
+func paragraphs(s string) []string {
+	// Paragraphs are separated by one or more empty lines.
+	var paragraphs []string
+	var lines []string
+
+	flush := func() {
+		if len(lines) > 0 {
+			paragraphs = append(paragraphs, strings.Join(lines, "\n"))
+			lines = nil
+		}
+	}
+
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) == "" {
+			flush()
+		} else {
+			lines = append(lines, line)
+		}
+	}
+
+	flush()
+
+	return paragraphs
+}
+
+func suggestions(word string, candidates []string) []string {
+	// Candidates are suggested if the word is a prefix of their name or if
+	// they are close enough to the word, keeping only the closest ones.
+	if word == "" {
+		return nil
+	}
+
+	maxDistance := min(2, len([]rune(word))/2)
+
+	var prefixMatches []string
+	var closestMatches []string
+	closestDistance := maxDistance + 1
+
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			prefixMatches = append(prefixMatches, candidate)
+			continue
+		}
+
+		d := editDistance(word, candidate)
+		if d > maxDistance {
+			continue
+		}
+
+		if d < closestDistance {
+			closestMatches = []string{candidate}
+			closestDistance = d
+		} else if d == closestDistance {
+			closestMatches = append(closestMatches, candidate)
+		}
+	}
+
+	matches := append(prefixMatches, closestMatches...)
+	slices.Sort(matches)
+
+	return slices.Compact(matches)
+}
+
+func editDistance(s1, s2 string) int {
+	// Optimal string alignment distance, i.e. the Levenshtein distance where
+	// the transposition of two adjacent characters counts as one edit.
+	r1 := []rune(s1)
+	r2 := []rune(s2)
+
+	d := make([][]int, len(r1)+1)
+	for i := range d {
+		d[i] = make([]int, len(r2)+1)
+		d[i][0] = i
+	}
+
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(r1); i++ {
+		for j := 1; j <= len(r2); j++ {
+			cost := 1
+			if r1[i-1] == r2[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+
+			if i > 1 && j > 1 && r1[i-1] == r2[j-2] && r1[i-2] == r2[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(r1)][len(r2)]
+}
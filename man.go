@@ -0,0 +1,364 @@
+package program
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+)
+
+type ManPageSection struct {
+	Title   string
+	Content string
+}
+
+func (p *Program) ManPageName(cmd *Command) string {
+	return p.pageName(cmd)
+}
+
+func (p *Program) pageName(cmd *Command) string {
+	if cmd == nil || cmd.FullName == "" {
+		return p.Name
+	}
+
+	return p.Name + "-" + strings.Join(splitCommandName(cmd.FullName), "-")
+}
+
+func (p *Program) WriteManPages(dirPath string, perCommand bool) error {
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return fmt.Errorf("cannot create directory %q: %w", dirPath, err)
+	}
+
+	cmds := []*Command{p.command}
+	if perCommand {
+		cmds = append(cmds, p.manPageCommands()...)
+	}
+
+	for _, cmd := range cmds {
+		var buf bytes.Buffer
+
+		if err := p.WriteManPage(&buf, cmd); err != nil {
+			return err
+		}
+
+		filePath := filepath.Join(dirPath, p.ManPageName(cmd)+".1")
+
+		if err := os.WriteFile(filePath, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("cannot write %q: %w", filePath, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Program) WriteManPage(w io.Writer, cmd *Command) error {
+	if cmd != nil && cmd.FullName == "" {
+		cmd = nil
+	}
+
+	if p.command != nil {
+		p.addDefaultCommandsOnce()
+	}
+
+	var buf bytes.Buffer
+
+	name := p.ManPageName(cmd)
+
+	fmt.Fprintf(&buf, ".TH %s 1\n", roffEscape(strings.ToUpper(name)))
+
+	// NAME
+	description := p.Description
+	if cmd != nil {
+		description = cmd.Description
+	}
+
+	fmt.Fprintf(&buf, ".SH NAME\n")
+	if description == "" {
+		fmt.Fprintf(&buf, "%s\n", roffEscape(name))
+	} else {
+		fmt.Fprintf(&buf, "%s \\- %s\n", roffEscape(name),
+			roffEscape(description))
+	}
+
+	// SYNOPSIS
+	usageCmd := cmd
+	if cmd == nil {
+		usageCmd = p.command
+	}
+
+	fmt.Fprintf(&buf, ".SH SYNOPSIS\n")
+	fmt.Fprintf(&buf, "%s\n", roffLine(p.usageLine(usageCmd)))
+
+	u := p.usage(usageCmd)
+
+	// DESCRIPTION
+	if description != "" || len(u.LongDescription) > 0 {
+		fmt.Fprintf(&buf, ".SH DESCRIPTION\n")
+
+		if description != "" {
+			fmt.Fprintf(&buf, "%s\n", roffLine(sentence(description)))
+		}
+
+		for i, paragraph := range u.LongDescription {
+			if i > 0 || description != "" {
+				fmt.Fprintf(&buf, ".PP\n")
+			}
+
+			writeRoffLines(&buf, paragraph)
+		}
+	}
+
+	// COMMANDS
+	if cmd == nil && p.command != nil {
+		fmt.Fprintf(&buf, ".SH COMMANDS\n")
+
+		for _, cmd2 := range p.manPageCommands() {
+			fmt.Fprintf(&buf, ".TP\n")
+			fmt.Fprintf(&buf, "\\fB%s\\fR", roffEscape(cmd2.FullName))
+
+			for _, arg := range cmd2.arguments {
+				fmt.Fprintf(&buf, " %s", roffEscape(arg.usageString()))
+			}
+
+			fmt.Fprintf(&buf, "\n%s\n", roffLine(cmd2.Description))
+		}
+	}
+
+	// ARGUMENTS
+	var arguments []*Argument
+	if cmd == nil {
+		arguments = p.arguments
+	} else {
+		arguments = cmd.arguments
+	}
+
+	if len(arguments) > 0 {
+		fmt.Fprintf(&buf, ".SH ARGUMENTS\n")
+
+		for _, arg := range arguments {
+			fmt.Fprintf(&buf, ".TP\n")
+			fmt.Fprintf(&buf, "\\fI%s\\fR\n", roffEscape(arg.Name))
+			fmt.Fprintf(&buf, "%s\n", roffLine(arg.Description))
+		}
+	}
+
+	// OPTIONS
+	for _, section := range u.OptionSections {
+		writeManPageOptions(&buf, section)
+	}
+
+	// ENVIRONMENT, unless provided as additional section
+	hasEnvironmentSection := false
+	for _, section := range p.ManPageSections {
+		if strings.ToUpper(section.Title) == "ENVIRONMENT" {
+			hasEnvironmentSection = true
+		}
+	}
+
+	if !hasEnvironmentSection || cmd != nil {
+		p.writeManPageEnvironment(&buf, cmd)
+	}
+
+	// EXAMPLES
+	if len(u.Examples) > 0 {
+		fmt.Fprintf(&buf, ".SH EXAMPLES\n")
+
+		for _, example := range u.Examples {
+			fmt.Fprintf(&buf, ".TP\n")
+			fmt.Fprintf(&buf, "\\fB%s\\fR\n", roffEscape(example.Label))
+			fmt.Fprintf(&buf, "%s\n", roffLine(example.Description))
+		}
+	}
+
+	// Additional sections, only for the main page
+	if cmd == nil {
+		for _, section := range p.ManPageSections {
+			writeManPageSection(&buf, section)
+		}
+	}
+
+	// SEE ALSO
+	var seeAlso []string
+	if cmd == nil {
+		for _, cmd2 := range p.manPageCommands() {
+			seeAlso = append(seeAlso, p.ManPageName(cmd2))
+		}
+	} else {
+		seeAlso = append(seeAlso, p.Name)
+
+		// Command groups do not have their own man page.
+		for _, ref := range u.SeeAlso {
+			if ref.Command != nil && ref.Command.Main != nil {
+				seeAlso = append(seeAlso, p.ManPageName(ref.Command))
+			}
+		}
+	}
+
+	if len(seeAlso) > 0 {
+		fmt.Fprintf(&buf, ".SH SEE ALSO\n")
+
+		for i, name := range seeAlso {
+			fmt.Fprintf(&buf, ".BR %s (1)", roffEscape(name))
+			if i < len(seeAlso)-1 {
+				buf.WriteByte(',')
+			}
+
+			buf.WriteByte('\n')
+		}
+	}
+
+	_, err := io.Copy(w, &buf)
+	return err
+}
+
+func writeManPageSection(buf *bytes.Buffer, section ManPageSection) {
+	fmt.Fprintf(buf, ".SH %s\n", roffEscape(strings.ToUpper(section.Title)))
+
+	for i, paragraph := range strings.Split(section.Content, "\n\n") {
+		if i > 0 {
+			fmt.Fprintf(buf, ".PP\n")
+		}
+
+		writeRoffLines(buf, paragraph)
+	}
+}
+
+func writeRoffLines(buf *bytes.Buffer, s string) {
+	for _, line := range strings.Split(s, "\n") {
+		fmt.Fprintf(buf, "%s\n", roffLine(line))
+	}
+}
+
+func writeManPageOptions(buf *bytes.Buffer, section usageOptionSection) {
+	fmt.Fprintf(buf, ".SH %s\n", roffEscape(section.Label))
+
+	for _, uopt := range section.Options {
+		opt := uopt.Option
+
+		fmt.Fprintf(buf, ".TP\n")
+
+		var names []string
+
+		if opt.ShortName != "" {
+			names = append(names, "\\fB"+roffEscape("-"+opt.ShortName)+"\\fR")
+		}
+
+		if opt.LongName != "" {
+			names = append(names, "\\fB"+roffEscape("--"+opt.LongName)+"\\fR")
+		}
+
+		if opt.isNegatable() {
+			names = append(names,
+				"\\fB"+roffEscape("--no-"+opt.LongName)+"\\fR")
+		}
+
+		buf.WriteString(strings.Join(names, ", "))
+
+		if opt.takesValue() {
+			fmt.Fprintf(buf, " \\fI%s\\fR", roffEscape(opt.valueName()))
+
+			if opt.Repeatable {
+				buf.WriteString("...")
+			}
+		}
+
+		buf.WriteByte('\n')
+
+		fmt.Fprintf(buf, "%s\n", roffLine(uopt.Description))
+	}
+}
+
+func (p *Program) writeManPageEnvironment(buf *bytes.Buffer, cmd *Command) {
+	options := uniqueOptions(p.options)
+	if cmd != nil {
+		options = append(options, uniqueOptions(cmd.allOptions())...)
+	}
+
+	var lines []string
+
+	for _, opt := range options {
+		if opt.Hidden {
+			continue
+		}
+
+		name := p.optionEnvironmentVariable(opt)
+		if name == "" {
+			continue
+		}
+
+		optName := "--" + opt.LongName
+		if opt.LongName == "" {
+			optName = "-" + opt.ShortName
+		}
+
+		lines = append(lines, ".TP\n",
+			fmt.Sprintf("\\fB%s\\fR\n", roffEscape(name)),
+			fmt.Sprintf("Equivalent to the \\fB%s\\fR option.\n",
+				roffEscape(optName)))
+	}
+
+	if len(lines) > 0 {
+		fmt.Fprintf(buf, ".SH ENVIRONMENT\n")
+
+		for _, line := range lines {
+			buf.WriteString(line)
+		}
+	}
+}
+
+func (p *Program) manPageCommands() []*Command {
+	// All commands which can be executed, i.e. which are not only command
+	// groups, sorted by full name.
+
+	if p.command == nil {
+		return nil
+	}
+
+	var cmds []*Command
+
+	var fn func(*Command)
+	fn = func(cmd *Command) {
+		names := maps.Keys(cmd.subcommands)
+		slices.Sort(names)
+
+		for _, name := range names {
+			subcmd := cmd.subcommands[name]
+			if subcmd.Hidden {
+				continue
+			}
+
+			if subcmd.Main != nil {
+				cmds = append(cmds, subcmd)
+			}
+
+			fn(subcmd)
+		}
+	}
+
+	fn(p.command)
+
+	return cmds
+}
+
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	return s
+}
+
+func roffLine(s string) string {
+	// Lines starting with a control character would be interpreted as
+	// requests.
+	s = roffEscape(s)
+
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+
+	return s
+}
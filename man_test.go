@@ -0,0 +1,83 @@
+package program
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteManPage(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewProgram("test", "a test program")
+	p.AddOption("o", "output", "path", "-", "the output file")
+	p.AddOption("", "level", "level", "", "the log level").
+		EnvironmentVariable = "TEST_LEVEL"
+	p.AddArgument("name", "the name of the foo")
+	p.AddTrailingArgument("value", "additional values")
+
+	p.ManPageSections = []ManPageSection{
+		{"Exit status", "0 on success.\n\n1 on error."},
+		{"Examples", "test -o foo.txt a\n.b"},
+	}
+
+	var buf bytes.Buffer
+	if assert.NoError(p.WriteManPage(&buf, nil)) {
+		assert.Equal(`.TH TEST 1
+.SH NAME
+test \- a test program
+.SH SYNOPSIS
+test [OPTIONS] <name> [<value>...]
+.SH DESCRIPTION
+A test program.
+.SH ARGUMENTS
+.TP
+\fIname\fR
+the name of the foo
+.TP
+\fIvalue\fR
+additional values
+.SH OPTIONS
+.TP
+\fB\-\-debug\fR \fIlevel\fR
+print debug messages (default: "0")
+.TP
+\fB\-h\fR, \fB\-\-help\fR
+print help and exit
+.TP
+\fB\-\-level\fR \fIlevel\fR
+the log level (env: TEST_LEVEL)
+.TP
+\fB\-o\fR, \fB\-\-output\fR \fIpath\fR
+the output file (default: "\-")
+.TP
+\fB\-q\fR, \fB\-\-quiet\fR
+do not print status and information messages
+.SH ENVIRONMENT
+.TP
+\fBTEST_LEVEL\fR
+Equivalent to the \fB\-\-level\fR option.
+.SH EXIT STATUS
+0 on success.
+.PP
+1 on error.
+.SH EXAMPLES
+test \-o foo.txt a
+\&.b
+`, buf.String())
+	}
+
+	p = NewProgram("test", "")
+	c := p.AddCommand("foo", "", func(p *Program) {})
+	c.LongDescription = "First paragraph.\n\nSecond paragraph."
+
+	buf.Reset()
+	if assert.NoError(p.WriteManPage(&buf, c)) {
+		assert.Contains(buf.String(), `.SH DESCRIPTION
+First paragraph.
+.PP
+Second paragraph.
+.SH `)
+	}
+}
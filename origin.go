@@ -0,0 +1,81 @@
+package program
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type OptionSource string
+
+const (
+	OptionSourceDefault     OptionSource = "default"
+	OptionSourceCommandLine OptionSource = "command-line"
+	OptionSourceEnvironment OptionSource = "environment"
+	OptionSourceFile        OptionSource = "file"
+)
+
+type OptionOrigin struct {
+	Source OptionSource
+
+	EnvironmentVariable string // OptionSourceEnvironment only
+
+	FilePath string // OptionSourceFile only
+	Line     int    // OptionSourceFile only, zero if not available
+}
+
+func (o OptionOrigin) String() string {
+	switch o.Source {
+	case OptionSourceCommandLine:
+		return "command line"
+
+	case OptionSourceEnvironment:
+		return "environment variable " + o.EnvironmentVariable
+
+	case OptionSourceFile:
+		if o.Line == 0 {
+			return "file " + o.FilePath
+		}
+
+		return fmt.Sprintf("file %s:%d", o.FilePath, o.Line)
+	}
+
+	return "default value"
+}
+
+func (p *Program) OptionOrigin(name string) OptionOrigin {
+	return p.mustOption(name).Origin
+}
+
+func (p *Program) printOptionOrigins() {
+	t := NewTable()
+	t.AddColumn(TableColumn{Label: "option"})
+	t.AddColumn(TableColumn{Label: "value"})
+	t.AddColumn(TableColumn{Label: "origin"})
+
+	for _, opt := range p.selectedOptions() {
+		var value string
+
+		if opt.takesValue() {
+			var values []string
+			if opt.Set {
+				values = opt.Values
+			} else if opt.DefaultValue != "" {
+				values = []string{opt.DefaultValue}
+			}
+
+			quotedValues := make([]string, len(values))
+			for i, value := range values {
+				quotedValues[i] = strconv.Quote(value)
+			}
+
+			value = strings.Join(quotedValues, ", ")
+		} else {
+			value = strconv.FormatBool(opt.flagValue())
+		}
+
+		t.AddRow(opt.Name(), value, opt.Origin)
+	}
+
+	t.Write(p.Stderr, p.Stderr)
+}
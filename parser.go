@@ -2,75 +2,235 @@ package program
 
 import (
 	"maps"
-	"os"
 	"strings"
 )
 
-func (p *Program) parse() {
-	args := p.parseOptions(os.Args[1:], p.options)
+type ParsingMode string
 
-	if p.IsOptionSet("help") {
-		return
+const (
+	// Options must appear before arguments; the first argument which is not
+	// an option ends option parsing.
+	ParsingModePOSIX ParsingMode = "posix"
+
+	// Options and arguments can be interspersed; only "--" ends option
+	// parsing.
+	ParsingModeGNU ParsingMode = "gnu"
+)
+
+var commandLineOrigin = OptionOrigin{Source: OptionSourceCommandLine}
+
+func (p *Program) parsingMode() ParsingMode {
+	if p.ParsingMode == "" {
+		return ParsingModePOSIX
+	}
+
+	return p.ParsingMode
+}
+
+func (c *Command) parsingMode() ParsingMode {
+	if c.ParsingMode == "" {
+		return c.program.parsingMode()
 	}
 
+	return c.ParsingMode
+}
+
+func (p *Program) parse(args []string) error {
+	var endOfOptions bool
+	var err error
+
 	if p.command == nil {
-		args = p.parseArguments(args, p.arguments)
-	} else {
-		args = p.parseCommand(args)
+		args, _, err = p.parseOptions(args, p.options, p.parsingMode())
+		if err != nil {
+			return err
+		}
+
+		if p.IsOptionSet("help") {
+			return nil
+		}
 
+		return p.parseArguments(args, p.arguments)
+	}
+
+	// Global options always come before the command
+	args, endOfOptions, err = p.parseOptions(args, p.options, ParsingModePOSIX)
+	if err != nil {
+		return err
+	}
+
+	if p.IsOptionSet("help") {
+		return nil
+	}
+
+	args, endOfOptions, err = p.parseCommand(args, endOfOptions)
+	if err != nil {
+		return err
+	}
+
+	if !endOfOptions {
 		options := make(map[string]*Option)
 		maps.Copy(options, p.options)
-		maps.Copy(options, p.selectedCommand.options)
+		maps.Copy(options, p.selectedCommand.allOptions())
 
-		args = p.parseOptions(args, options)
+		mode := p.selectedCommand.parsingMode()
 
-		isHelpCommand := p.selectedCommand != nil &&
-			p.selectedCommand.FullName == "help"
-		if p.IsOptionSet("help") && !isHelpCommand {
-			return
+		args, _, err = p.parseOptions(args, options, mode)
+		if err != nil {
+			return err
 		}
+	}
 
-		args = p.parseArguments(args, p.selectedCommand.arguments)
+	isHelpCommand := p.selectedCommand != nil &&
+		p.selectedCommand.FullName == "help"
+	if p.IsOptionSet("help") && !isHelpCommand {
+		return nil
 	}
+
+	return p.parseArguments(args, p.selectedCommand.arguments)
 }
 
-func (p *Program) parseOptions(args []string, options map[string]*Option) []string {
+func (p *Program) parseOptions(args []string, options map[string]*Option, mode ParsingMode) ([]string, bool, error) {
+	var arguments []string
+	var endOfOptions bool
+
 	for len(args) > 0 {
 		arg := args[0]
-		if arg == "--" || !isOption(arg) {
+
+		if arg == "--" {
+			args = args[1:]
+			endOfOptions = true
 			break
 		}
 
-		key := strings.TrimLeft(arg, "-")
+		if !isOption(arg) {
+			if mode != ParsingModeGNU {
+				break
+			}
 
-		opt, found := options[key]
-		if !found {
-			p.Fatal("unknown option %q", key)
+			arguments = append(arguments, arg)
+			args = args[1:]
+			continue
 		}
 
-		opt.Set = true
+		args = args[1:]
+
+		var err error
+
+		if isLongOption(arg) {
+			args, err = p.parseLongOption(arg[2:], args, options)
+		} else {
+			args, err = p.parseShortOptions(arg[1:], args, options)
+		}
+
+		if err != nil {
+			return nil, false, err
+		}
+	}
 
-		if opt.ValueName == "" {
+	return append(arguments, args...), endOfOptions, nil
+}
+
+func (p *Program) parseLongOption(arg string, args []string, options map[string]*Option) ([]string, error) {
+	// Long options can be followed by their value, e.g. "--name=value".
+
+	name, value, hasValue := strings.Cut(arg, "=")
+
+	opt, found := options[name]
+	negated := false
+
+	if !found {
+		if baseName, ok := strings.CutPrefix(name, "no-"); ok {
+			opt, found = options[baseName]
+			negated = found && opt.isNegatable() && opt.LongName == baseName
+		}
+
+		if !negated {
+			return nil, &UnknownOptionError{
+				Option:      name,
+				Suggestions: optionSuggestions(name, options),
+			}
+		}
+	}
+
+	if !opt.takesValue() {
+		if hasValue {
+			return nil, &UnexpectedOptionValueError{Option: name}
+		}
+
+		opt.setFlag(!negated, commandLineOrigin)
+	} else {
+		if !hasValue {
+			if len(args) == 0 {
+				return nil, &MissingOptionValueError{Option: name}
+			}
+
+			value = args[0]
 			args = args[1:]
+		}
+
+		if err := opt.setValue(value, commandLineOrigin); err != nil {
+			return nil, &InvalidOptionValueError{
+				Option: name,
+				Value:  value,
+				Err:    err,
+			}
+		}
+	}
+
+	return args, nil
+}
+
+func (p *Program) parseShortOptions(names string, args []string, options map[string]*Option) ([]string, error) {
+	// Short options can be grouped (e.g. "-abc" is equivalent to "-a -b
+	// -c"). If an option in the group has a value, the rest of the group is
+	// used as value (e.g. "-afoo" is equivalent to "-a foo" if "a" has a
+	// value).
+
+	for i, c := range names {
+		name := string(c)
+
+		opt, found := options[name]
+		if !found {
+			return nil, &UnknownOptionError{Option: name}
+		}
+
+		if !opt.takesValue() {
+			opt.setFlag(true, commandLineOrigin)
+			continue
+		}
+
+		var value string
+
+		if rest := names[i+len(name):]; rest != "" {
+			value = rest
 		} else {
-			if len(args) < 2 {
-				p.Fatal("missing value for option %q", key)
+			if len(args) == 0 {
+				return nil, &MissingOptionValueError{Option: name}
 			}
 
-			opt.Value = args[1]
+			value = args[0]
+			args = args[1:]
+		}
 
-			args = args[2:]
+		if err := opt.setValue(value, commandLineOrigin); err != nil {
+			return nil, &InvalidOptionValueError{
+				Option: name,
+				Value:  value,
+				Err:    err,
+			}
 		}
+
+		break
 	}
 
-	return args
+	return args, nil
 }
 
-func (p *Program) parseCommand(args []string) []string {
+func (p *Program) parseCommand(args []string, endOfOptions bool) ([]string, bool, error) {
 	p.selectedCommand = p.command
 
 	if len(args) == 0 {
-		p.Fatal("missing command")
+		return nil, false, &MissingCommandError{}
 	}
 
 	cmd := p.command
@@ -78,13 +238,36 @@ func (p *Program) parseCommand(args []string) []string {
 
 	for len(args) > 0 {
 		arg := args[0]
-		if arg == "--" || isOption(arg) {
-			break
+		if !endOfOptions && (arg == "--" || isOption(arg)) {
+			if cmd == p.command || cmd.subcommands == nil {
+				break
+			}
+
+			// Options inherited from a group can be used right after the
+			// name of the group, e.g. "foo --namespace x create".
+			options := make(map[string]*Option)
+			maps.Copy(options, p.options)
+			maps.Copy(options, cmd.allOptions())
+
+			p.selectedCommand = cmd
+
+			var err error
+			args, endOfOptions, err = p.parseOptions(args, options,
+				ParsingModePOSIX)
+			if err != nil {
+				return nil, false, err
+			}
+
+			if p.IsOptionSet("help") {
+				break
+			}
+
+			continue
 		}
 
 		names = append(names, arg)
 
-		cmd2 := cmd.subcommands[arg]
+		cmd2 := cmd.findSubcommand(arg)
 		if cmd2 == nil {
 			break
 		}
@@ -93,88 +276,95 @@ func (p *Program) parseCommand(args []string) []string {
 		args = args[1:]
 	}
 
-	fullName := strings.Join(names, " ")
-
 	if cmd == p.command {
-		p.Fatal("unknown command %q", fullName)
+		err := UnknownCommandError{Command: names}
+		if len(names) > 0 {
+			err.Suggestions = cmd.subcommandSuggestions(names[len(names)-1])
+		}
+
+		return nil, false, &err
 	}
 
-	if cmd.Main == nil {
+	p.selectedCommand = cmd
+
+	if cmd.Main == nil && !p.IsOptionSet("help") {
 		if len(args) == 0 {
-			p.Fatal("missing subcommand(s) for command %q", cmd.FullName)
-		} else {
-			if args[0] != "-h" {
-				p.Fatal("unknown command %q", strings.Join(names, " "))
-			}
+			return nil, false, &MissingCommandError{Command: cmd.FullName}
 		}
-	}
 
-	p.selectedCommand = cmd
+		return nil, false, &UnknownCommandError{
+			Command:     names,
+			Suggestions: cmd.subcommandSuggestions(args[0]),
+		}
+	}
 
-	return args
+	return args, endOfOptions, nil
 }
 
-func (p *Program) parseArguments(args []string, arguments []*Argument) []string {
-	if len(arguments) > 0 {
-		// Mandatory arguments
-		min := 0
-		for _, argument := range arguments {
-			if argument.Optional || argument.Trailing {
-				break
-			}
-
-			min++
+func (p *Program) parseArguments(args []string, arguments []*Argument) error {
+	if len(arguments) == 0 {
+		if len(args) > 0 {
+			return &TooManyArgumentsError{Arguments: args}
 		}
 
-		if len(args) < min {
-			p.Fatal("missing argument(s)")
+		return nil
+	}
+
+	// Mandatory arguments
+	min := 0
+	for _, argument := range arguments {
+		if argument.Optional || argument.Trailing {
+			break
 		}
 
-		for i := 0; i < min; i++ {
-			argument := arguments[i]
+		min++
+	}
+
+	if len(args) < min {
+		return &MissingArgumentError{Argument: arguments[len(args)].Name}
+	}
 
-			argument.Set = true
-			argument.Value = args[i]
+	for i := 0; i < min; i++ {
+		if err := arguments[i].setValue(args[i]); err != nil {
+			return err
 		}
+	}
 
-		args = args[min:]
-		arguments = arguments[min:]
+	args = args[min:]
+	arguments = arguments[min:]
 
-		// Optional arguments
-		var trailingArgument *Argument
+	// Optional arguments
+	var trailingArgument *Argument
 
-		for _, argument := range arguments {
-			if len(args) == 0 {
-				break
-			}
-
-			if argument.Trailing {
-				trailingArgument = argument
-				break
-			}
+	for _, argument := range arguments {
+		if len(args) == 0 {
+			break
+		}
 
-			argument.Set = true
-			argument.Value = args[0]
+		if argument.Trailing {
+			trailingArgument = argument
+			break
+		}
 
-			args = args[1:]
+		if err := argument.setValue(args[0]); err != nil {
+			return err
 		}
 
-		// Trailing argument
-		if trailingArgument != nil {
-			trailingArgument.TrailingValues = args
-			args = args[len(args):]
-		} else {
-			if len(args) > 0 {
-				p.Fatal("too many arguments")
+		args = args[1:]
+	}
+
+	// Trailing argument
+	if trailingArgument != nil {
+		for _, arg := range args {
+			if err := trailingArgument.setValue(arg); err != nil {
+				return err
 			}
 		}
-	} else {
-		if len(args) > 0 {
-			p.Fatal("unexpected arguments")
-		}
+	} else if len(args) > 0 {
+		return &TooManyArgumentsError{Arguments: args}
 	}
 
-	return args
+	return nil
 }
 
 func isOption(arg string) bool {
@@ -182,7 +372,7 @@ func isOption(arg string) bool {
 }
 
 func isShortOption(arg string) bool {
-	return len(arg) == 2 && arg[0] == '-' && arg[1] != '-'
+	return len(arg) > 1 && arg[0] == '-' && arg[1] != '-'
 }
 
 func isLongOption(arg string) bool {
@@ -0,0 +1,582 @@
+package program
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseShortOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewProgram("test", "")
+	p.AddFlag("a", "", "")
+	p.AddFlag("b", "", "")
+	p.AddOption("c", "", "value", "", "")
+	p.AddTrailingArgument("arg", "")
+
+	if assert.NoError(p.ParseArgs([]string{"-ab", "x"})) {
+		assert.True(p.IsOptionSet("a"))
+		assert.True(p.IsOptionSet("b"))
+		assert.False(p.IsOptionSet("c"))
+		assert.Equal([]string{"x"}, p.TrailingArgumentValues("arg"))
+	}
+
+	if assert.NoError(p.ParseArgs([]string{"-bc", "foo", "x"})) {
+		assert.False(p.IsOptionSet("a"))
+		assert.True(p.IsOptionSet("b"))
+		assert.Equal("foo", p.OptionValue("c"))
+		assert.Equal([]string{"x"}, p.TrailingArgumentValues("arg"))
+	}
+
+	if assert.NoError(p.ParseArgs([]string{"-a", "-", "x"})) {
+		assert.True(p.IsOptionSet("a"))
+		assert.Equal([]string{"-", "x"}, p.TrailingArgumentValues("arg"))
+	}
+}
+
+func TestParseOptionValues(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewProgram("test", "")
+	p.AddFlag("a", "", "")
+	p.AddOption("c", "option-c", "value", "", "")
+
+	tests := []struct {
+		args  []string
+		value string
+	}{
+		{[]string{"-c", "foo"}, "foo"},
+		{[]string{"-cfoo"}, "foo"},
+		{[]string{"-ac-foo"}, "-foo"},
+		{[]string{"-c", "-foo"}, "-foo"},
+		{[]string{"--option-c", "foo"}, "foo"},
+		{[]string{"--option-c=foo"}, "foo"},
+		{[]string{"--option-c=-foo"}, "-foo"},
+		{[]string{"--option-c=a=b"}, "a=b"},
+		{[]string{"--option-c="}, ""},
+	}
+
+	for _, test := range tests {
+		label := fmt.Sprintf("%q", test.args)
+
+		if assert.NoError(p.ParseArgs(test.args), label) {
+			assert.True(p.IsOptionSet("c"), label)
+			assert.Equal(test.value, p.OptionValue("c"), label)
+		}
+	}
+}
+
+func TestParseRepeatableOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewProgram("test", "")
+	p.AddFlag("v", "verbose", "").Repeatable = true
+	p.AddOption("I", "include", "path", "", "").Repeatable = true
+	p.AddOption("c", "", "value", "foo", "")
+
+	if assert.NoError(p.ParseArgs([]string{})) {
+		assert.Equal(0, p.OptionCount("verbose"))
+		assert.Nil(p.OptionValues("include"))
+		assert.Equal([]string{"foo"}, p.OptionValues("c"))
+	}
+
+	args := []string{"-vIa", "-vv", "--include=b", "-c", "x", "-I", "c",
+		"--verbose", "-cy"}
+	if assert.NoError(p.ParseArgs(args)) {
+		assert.Equal(4, p.OptionCount("verbose"))
+		assert.Equal([]string{"a", "b", "c"}, p.OptionValues("include"))
+		assert.Equal("c", p.OptionValue("include"))
+		assert.Equal([]string{"y"}, p.OptionValues("c"))
+		assert.Equal("y", p.OptionValue("c"))
+	}
+}
+
+func TestParseTypedValues(t *testing.T) {
+	assert := assert.New(t)
+
+	var count IntegerValue
+	var format EnumValue
+	var timeout DurationValue
+	var addresses []IPAddressValue
+
+	p := NewProgram("test", "")
+	p.AddOption("n", "", "", "10", "").TypedValue = &count
+	p.AddOption("f", "", "", "", "").TypedValue = &format
+	p.AddArgument("timeout", "").TypedValue = &timeout
+	p.AddTrailingArgument("address", "").TypedValue =
+		valueFunc(func(s string) error {
+			var address IPAddressValue
+			if err := address.Parse(s); err != nil {
+				return err
+			}
+
+			addresses = append(addresses, address)
+			return nil
+		})
+
+	format.Values = []string{"json", "text"}
+
+	args := []string{"-fjson", "1m", "127.0.0.1", "::1"}
+	if assert.NoError(p.ParseArgs(args)) {
+		assert.Equal(int64(10), count.Value)
+		assert.Equal("json", format.Value)
+		assert.Equal(time.Minute, timeout.Value)
+		if assert.Len(addresses, 2) {
+			assert.Equal("127.0.0.1", addresses[0].String())
+			assert.Equal("::1", addresses[1].String())
+		}
+	}
+
+	args = []string{"-n", "5", "-fjson", "1m"}
+	if assert.NoError(p.ParseArgs(args)) {
+		assert.Equal(int64(5), count.Value)
+		assert.Equal("json", format.Value)
+	}
+
+	args = []string{"1m"}
+	if assert.NoError(p.ParseArgs(args)) {
+		assert.Equal(int64(10), count.Value)
+		assert.Equal("", format.Value)
+		assert.Equal([]string{"json", "text"}, format.Values)
+	}
+
+	err := p.ParseArgs([]string{"-n", "x", "1m"})
+	if assert.IsType(&InvalidOptionValueError{}, err) {
+		assert.EqualError(err,
+			`invalid value "x" for option "n": must be an integer`)
+	}
+
+	err = p.ParseArgs([]string{"-fxml", "1m"})
+	assert.IsType(&InvalidOptionValueError{}, err)
+
+	err = p.ParseArgs([]string{"1m", "127.0.0.1", "foo"})
+	if assert.IsType(&InvalidArgumentValueError{}, err) {
+		assert.EqualError(err, `invalid value "foo" for argument "address": `+
+			`must be a valid IP address`)
+	}
+}
+
+type valueFunc func(string) error
+
+func (f valueFunc) Parse(s string) error {
+	return f(s)
+}
+
+func (f valueFunc) String() string {
+	return ""
+}
+
+func (f valueFunc) TypeName() string {
+	return "value"
+}
+
+func TestParseParsingModes(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewProgram("test", "")
+	p.AddFlag("a", "", "")
+
+	c := p.AddCommand("posix", "", func(p *Program) {})
+	c.AddFlag("b", "", "")
+	c.AddTrailingArgument("args", "")
+
+	c = p.AddCommand("gnu", "", func(p *Program) {})
+	c.ParsingMode = ParsingModeGNU
+	c.AddFlag("b", "", "")
+	c.AddTrailingArgument("args", "")
+
+	tests := []struct {
+		args     []string
+		a, b     bool
+		trailing []string
+	}{
+		{[]string{"posix", "-b", "x", "y"},
+			false, true, []string{"x", "y"}},
+		{[]string{"posix", "x", "-b", "y"},
+			false, false, []string{"x", "-b", "y"}},
+		{[]string{"posix", "-b", "--", "-a", "x"},
+			false, true, []string{"-a", "x"}},
+		{[]string{"-a", "--", "posix", "-b"},
+			true, false, []string{"-b"}},
+		{[]string{"gnu", "x", "-b", "y", "-a"},
+			true, true, []string{"x", "y"}},
+		{[]string{"gnu", "x", "-b", "--", "y", "-a"},
+			false, true, []string{"x", "y", "-a"}},
+	}
+
+	for _, test := range tests {
+		label := fmt.Sprintf("%q", test.args)
+
+		if assert.NoError(p.ParseArgs(test.args), label) {
+			assert.Equal(test.a, p.IsOptionSet("a"), label)
+			assert.Equal(test.b, p.IsOptionSet("b"), label)
+			assert.Equal(test.trailing, p.TrailingArgumentValues("args"),
+				label)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewProgram("test", "")
+	p.AddFlag("a", "flag-a", "")
+	p.AddOption("c", "", "value", "", "")
+
+	c := p.AddCommand("foo bar", "", func(p *Program) {})
+	c.AddArgument("arg", "")
+
+	tests := []struct {
+		args []string
+		err  error
+	}{
+		{[]string{},
+			&MissingCommandError{}},
+		{[]string{"-x"},
+			&UnknownOptionError{Option: "x"}},
+		{[]string{"-ax"},
+			&UnknownOptionError{Option: "x"}},
+		{[]string{"--flag-b"},
+			&UnknownOptionError{Option: "flag-b",
+				Suggestions: []string{"flag-a"}}},
+		{[]string{"--flag-a=true"},
+			&UnexpectedOptionValueError{Option: "flag-a"}},
+		{[]string{"-c"},
+			&MissingOptionValueError{Option: "c"}},
+		{[]string{"baz"},
+			&UnknownCommandError{Command: []string{"baz"}}},
+		{[]string{"foo"},
+			&MissingCommandError{Command: "foo"}},
+		{[]string{"foo", "baz"},
+			&UnknownCommandError{Command: []string{"foo", "baz"},
+				Suggestions: []string{"bar"}}},
+		{[]string{"foo", "bar"},
+			&MissingArgumentError{Argument: "arg"}},
+		{[]string{"foo", "bar", "x", "y"},
+			&TooManyArgumentsError{Arguments: []string{"y"}}},
+		{[]string{"--debug", "x", "foo", "bar", "y"},
+			&InvalidOptionValueError{Option: "debug", Value: "x",
+				Err: fmt.Errorf("invalid debug level")}},
+	}
+
+	for _, test := range tests {
+		label := fmt.Sprintf("%q", test.args)
+		assert.Equal(test.err, p.ParseArgs(test.args), label)
+	}
+}
+
+func TestCommandAliases(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewProgram("test", "")
+
+	p.AddCommand("foo delete", "", func(p *Program) {}).
+		Aliases = []string{"rm", "remove"}
+	p.AddCommand("foo describe", "", func(p *Program) {})
+	p.AddCommand("bar", "", func(p *Program) {})
+
+	tests := []struct {
+		args     []string
+		prefixes bool
+		command  string
+		err      error
+	}{
+		{[]string{"foo", "delete"}, false, "foo delete", nil},
+		{[]string{"foo", "rm"}, false, "foo delete", nil},
+		{[]string{"foo", "remove"}, false, "foo delete", nil},
+		{[]string{"fo", "rm"}, false, "",
+			&UnknownCommandError{Command: []string{"fo"},
+				Suggestions: []string{"foo"}}},
+		{[]string{"fo", "rm"}, true, "foo delete", nil},
+		{[]string{"foo", "del"}, true, "foo delete", nil},
+		{[]string{"foo", "rem"}, true, "foo delete", nil},
+		{[]string{"foo", "de"}, true, "",
+			&UnknownCommandError{Command: []string{"foo", "de"},
+				Suggestions: []string{"delete", "describe"}}},
+		{[]string{"foo", "dleete"}, false, "",
+			&UnknownCommandError{Command: []string{"foo", "dleete"},
+				Suggestions: []string{"delete"}}},
+		{[]string{"foo", "xyz"}, false, "",
+			&UnknownCommandError{Command: []string{"foo", "xyz"}}},
+		{[]string{""}, false, "",
+			&UnknownCommandError{Command: []string{""}}},
+		{[]string{"foo", ""}, true, "",
+			&UnknownCommandError{Command: []string{"foo", ""}}},
+	}
+
+	for _, test := range tests {
+		label := fmt.Sprintf("%q", test.args)
+
+		p.CommandPrefixMatching = test.prefixes
+
+		err := p.ParseArgs(test.args)
+		if test.err == nil {
+			if assert.NoError(err, label) {
+				assert.Equal(test.command, p.selectedCommand.FullName, label)
+			}
+		} else {
+			assert.Equal(test.err, err, label)
+		}
+	}
+}
+
+func TestSuggestionMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(`unknown option "colr"; did you mean "--color"?`,
+		(&UnknownOptionError{Option: "colr",
+			Suggestions: []string{"color"}}).Error())
+	assert.Equal(`unknown command "foo de"; did you mean "delete" or "describe"?`,
+		(&UnknownCommandError{Command: []string{"foo", "de"},
+			Suggestions: []string{"delete", "describe"}}).Error())
+}
+
+func TestDeprecations(t *testing.T) {
+	assert := assert.New(t)
+
+	var stderr bytes.Buffer
+
+	p := NewProgram("test", "")
+	p.Stderr = &stderr
+
+	p.AddFlag("", "old-flag", "").Deprecated = `use "--new-flag" instead`
+	p.AddFlag("", "new-flag", "")
+
+	p.AddCommand("delete", "", func(p *Program) {}).
+		Deprecated = `use "remove" instead`
+	p.AddCommand("remove", "", func(p *Program) {})
+
+	tests := []struct {
+		args     []string
+		warnings string
+		err      error
+	}{
+		{[]string{"--new-flag", "remove"}, "", nil},
+		{[]string{"--old-flag", "remove"},
+			"warning: option \"old-flag\" is deprecated: " +
+				"use \"--new-flag\" instead\n",
+			&DeprecatedOptionError{Option: "old-flag",
+				Message: `use "--new-flag" instead`}},
+		{[]string{"delete"},
+			"warning: command \"delete\" is deprecated: " +
+				"use \"remove\" instead\n",
+			&DeprecatedCommandError{Command: "delete",
+				Message: `use "remove" instead`}},
+	}
+
+	for _, test := range tests {
+		label := fmt.Sprintf("%q", test.args)
+
+		stderr.Reset()
+		p.DeprecationErrors = false
+
+		if assert.NoError(p.ParseArgs(test.args), label) {
+			assert.Equal(test.warnings, stderr.String(), label)
+		}
+
+		stderr.Reset()
+		p.DeprecationErrors = true
+
+		assert.Equal(test.err, p.ParseArgs(test.args), label)
+		assert.Empty(stderr.String(), label)
+	}
+}
+
+func TestOptionConstraints(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewProgram("test", "")
+	p.AddOption("", "token", "token", "", "").Required = true
+
+	c := p.AddCommand("get", "", func(p *Program) {})
+	c.AddOption("f", "file", "path", "", "")
+	c.AddOption("u", "url", "uri", "", "")
+	c.AddOption("", "user", "name", "", "")
+	c.AddOption("", "password", "password", "", "")
+	c.AddFlag("j", "json", "")
+	c.AddFlag("y", "yaml", "")
+
+	c.AddRequiredOptionGroup("file", "url")
+	c.AddExclusiveOptionGroup("file", "url")
+	c.AddExclusiveOptionGroup("json", "yaml")
+	c.AddOptionDependency("password", "user")
+
+	assert.Panics(func() { c.AddOptionDependency("password", "nope") })
+
+	tests := []struct {
+		args []string
+		err  error
+	}{
+		{[]string{"--token", "x", "get", "-f", "a"}, nil},
+		{[]string{"--token", "x", "get", "-u", "a", "-j"}, nil},
+		{[]string{"--token", "x", "get", "-u", "a", "--user", "b",
+			"--password", "c"}, nil},
+		{[]string{"get", "-f", "a"},
+			&MissingOptionError{Option: "token"}},
+		{[]string{"--token", "x", "get"},
+			&MissingOptionGroupError{Options: []string{"file", "url"}}},
+		{[]string{"--token", "x", "get", "-f", "a", "-u", "b"},
+			&ConflictingOptionsError{Options: []string{"file", "url"}}},
+		{[]string{"--token", "x", "get", "-f", "a", "-jy"},
+			&ConflictingOptionsError{Options: []string{"json", "yaml"}}},
+		{[]string{"--token", "x", "get", "-f", "a", "--password", "c"},
+			&MissingRequiredOptionError{Option: "password",
+				RequiredOption: "user"}},
+		{[]string{"-h"}, nil},
+		{[]string{"help"}, nil},
+		{[]string{"help", "get"}, nil},
+		{[]string{"completion", "bash"}, nil},
+	}
+
+	for _, test := range tests {
+		label := fmt.Sprintf("%q", test.args)
+		assert.Equal(test.err, p.ParseArgs(test.args), label)
+	}
+
+	assert.Equal("test [GLOBAL OPTIONS] --token <token> get "+
+		"(--file <path> | --url <uri>) [--json | --yaml]", p.usageLine(c))
+
+	assert.Equal(`options "a", "b" and "c" cannot be used together`,
+		(&ConflictingOptionsError{Options: []string{"a", "b", "c"}}).Error())
+}
+
+func TestNegatableFlags(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewProgram("test", "")
+	p.EnvironmentVariablePrefix = "TEST_"
+
+	color := p.AddFlag("c", "color", "")
+	color.DefaultValue = "true"
+
+	p.AddFlag("", "cache", "").Negatable = true
+	p.AddFlag("", "verbose", "")
+
+	tests := []struct {
+		args        []string
+		environment map[string]string
+		color       bool
+		cache       *bool
+	}{
+		{[]string{}, nil, true, nil},
+		{[]string{"--color"}, nil, true, nil},
+		{[]string{"-c"}, nil, true, nil},
+		{[]string{"--no-color"}, nil, false, nil},
+		{[]string{"--no-color", "--color"}, nil, true, nil},
+		{[]string{"--cache"}, nil, true, ptr(true)},
+		{[]string{"--no-cache"}, nil, true, ptr(false)},
+		{[]string{}, map[string]string{"TEST_CACHE": "false"},
+			true, ptr(false)},
+		{[]string{"--cache"}, map[string]string{"TEST_CACHE": "false"},
+			true, ptr(true)},
+		{[]string{"--color"}, map[string]string{"TEST_COLOR": "false"},
+			true, nil},
+		{[]string{}, map[string]string{"TEST_COLOR": "false"},
+			false, nil},
+	}
+
+	for _, test := range tests {
+		label := fmt.Sprintf("%q %v", test.args, test.environment)
+
+		for _, name := range []string{"TEST_COLOR", "TEST_CACHE"} {
+			t.Setenv(name, test.environment[name])
+		}
+
+		if assert.NoError(p.ParseArgs(test.args), label) {
+			assert.Equal(test.color, p.BooleanOptionValue("color"), label)
+			assert.Equal(test.cache, p.OptionalBooleanOptionValue("cache"),
+				label)
+		}
+	}
+
+	assert.Equal(&UnknownOptionError{Option: "no-verbose"},
+		p.ParseArgs([]string{"--no-verbose"}))
+	assert.Equal(&UnknownOptionError{Option: "no-colr",
+		Suggestions: []string{"no-color"}}, p.ParseArgs([]string{"--no-colr"}))
+	assert.Equal(&UnexpectedOptionValueError{Option: "no-color"},
+		p.ParseArgs([]string{"--no-color=true"}))
+
+	assert.Equal("-c, --[no-]color", color.usageLabel())
+
+	var stderr bytes.Buffer
+	p.Stderr = &stderr
+
+	if assert.NoError(p.ParseArgs([]string{"--no-color"})) {
+		p.printOptionOrigins()
+
+		assert.Regexp(`(?m)^color +false +command line *$`, stderr.String())
+		assert.Regexp(`(?m)^cache +false +default value *$`, stderr.String())
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestCommandGroupOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewProgram("test", "")
+
+	foo := p.AddCommandGroup("foo", "manipulate foos")
+	foo.AddOption("n", "namespace", "name", "default", "the namespace")
+
+	create := p.AddCommand("foo create", "create a foo", func(p *Program) {})
+	create.AddFlag("f", "force", "")
+
+	p.AddCommand("foo delete", "delete a foo", func(p *Program) {})
+	p.AddCommand("bar", "", func(p *Program) {})
+
+	assert.Same(foo, p.AddCommandGroup("foo", "manipulate foos"))
+
+	tests := []struct {
+		args      []string
+		namespace string
+		err       error
+	}{
+		{[]string{"foo", "create"}, "default", nil},
+		{[]string{"foo", "create", "-fn", "x"}, "x", nil},
+		{[]string{"foo", "delete", "--namespace", "y"}, "y", nil},
+		{[]string{"foo", "-n", "x", "create"}, "x", nil},
+		{[]string{"foo", "--namespace=x", "create", "-f"}, "x", nil},
+		{[]string{"foo", "-f", "create"}, "",
+			&UnknownOptionError{Option: "f"}},
+		{[]string{"foo", "--", "-h"}, "",
+			&UnknownCommandError{Command: []string{"foo", "-h"}}},
+		{[]string{"bar", "--namespace", "y"}, "",
+			&UnknownOptionError{Option: "namespace"}},
+	}
+
+	for _, test := range tests {
+		label := fmt.Sprintf("%q", test.args)
+
+		err := p.ParseArgs(test.args)
+		if test.err == nil {
+			if assert.NoError(err, label) {
+				assert.Equal(test.namespace, p.OptionValue("namespace"),
+					label)
+			}
+		} else {
+			assert.Equal(test.err, err, label)
+		}
+	}
+
+	assert.Panics(func() { create.AddFlag("n", "", "") })
+	assert.Panics(func() { foo.AddFlag("f", "", "") })
+	assert.Panics(func() { p.AddCommand("bar baz", "", nil) })
+
+	var stderr bytes.Buffer
+	p.Stderr = &stderr
+	p.PrintUsage(create)
+
+	assert.Contains(stderr.String(), `
+COMMAND OPTIONS
+
+-f, --force
+-n, --namespace <name>  the namespace (default: "default")
+`)
+}
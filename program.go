@@ -2,6 +2,7 @@ package program
 
 import (
 	"fmt"
+	"io"
 	"os"
 )
 
@@ -11,15 +12,52 @@ type Program struct {
 	Name        string
 	Description string
 	Main        Main
+	ParsingMode ParsingMode
+
+	// If set, options without an explicit environment variable can be set
+	// with an environment variable whose name is the prefix followed by the
+	// long name of the option, e.g. "FOO_DRY_RUN" for "dry-run" with the
+	// "FOO_" prefix.
+	EnvironmentVariablePrefix string
+
+	// If set, commands can be selected with any unique prefix of their name
+	// or of one of their aliases, e.g. "del" for "delete".
+	CommandPrefixMatching bool
+
+	// If set, using deprecated commands or options is an error instead of
+	// printing a warning, e.g. to detect them in continuous integration.
+	DeprecationErrors bool
+
+	// If set, options are listed in usage information in the order they were
+	// declared instead of being sorted by name.
+	PreserveOptionOrder bool
+
+	// Additional sections for the manual page of the program, e.g. EXAMPLES
+	// or EXIT STATUS. Paragraphs are separated by empty lines.
+	ManPageSections []ManPageSection
 
 	command   *Command
 	options   map[string]*Option
 	arguments []*Argument
 
+	optionConstraints []*optionConstraint
+
 	selectedCommand *Command
 
+	bindings []*binding
+
+	optionCount int
+
+	defaultCommandsAdded      bool
+	configurationFilesEnabled bool
+
 	Quiet      bool
 	DebugLevel int
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Exit   func(int)
 }
 
 func NewProgram(name, description string) *Program {
@@ -28,6 +66,11 @@ func NewProgram(name, description string) *Program {
 		Description: description,
 
 		options: make(map[string]*Option),
+
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Exit:   os.Exit,
 	}
 
 	p.addDefaultOptions()
@@ -63,7 +106,7 @@ func (p *Program) Debug(level int, format string, args ...interface{}) {
 		return
 	}
 
-	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	fmt.Fprintf(p.Stderr, format+"\n", args...)
 }
 
 func (p *Program) Info(format string, args ...interface{}) {
@@ -71,14 +114,22 @@ func (p *Program) Info(format string, args ...interface{}) {
 		return
 	}
 
-	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	fmt.Fprintf(p.Stderr, format+"\n", args...)
+}
+
+func (p *Program) Warning(format string, args ...interface{}) {
+	fmt.Fprintf(p.Stderr, "warning: "+format+"\n", args...)
 }
 
 func (p *Program) Error(format string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, "error: "+format+"\n", args...)
+	fmt.Fprintf(p.Stderr, "error: "+format+"\n", args...)
 }
 
 func (p *Program) Fatal(format string, args ...interface{}) {
 	p.Error(format, args...)
-	os.Exit(1)
+	p.Exit(1)
+}
+
+func (p *Program) PrintTable(t *Table) {
+	t.Write(p.Stdout, p.Stderr)
 }
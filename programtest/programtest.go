@@ -0,0 +1,126 @@
+package programtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.n16f.net/program"
+)
+
+// If this environment variable is set to a non-empty value, golden files are
+// updated instead of being compared to the output of the program.
+const UpdateGoldenFilesEnvironmentVariable = "PROGRAMTEST_UPDATE"
+
+type Test struct {
+	Args        []string
+	Environment map[string]string
+	Stdin       string
+}
+
+type Result struct {
+	Stdout     string
+	Stderr     string
+	ExitStatus int
+}
+
+type exit struct {
+	status int
+}
+
+// Run parses the arguments and executes the program in the current process,
+// capturing its output and exit status. Environment variables are set with
+// testing.TB.Setenv, so Run cannot be used in parallel tests.
+func Run(t testing.TB, p *program.Program, test Test) *Result {
+	t.Helper()
+
+	for name, value := range test.Environment {
+		t.Setenv(name, value)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	p.Stdin = strings.NewReader(test.Stdin)
+	p.Stdout = &stdout
+	p.Stderr = &stderr
+	p.Exit = func(status int) {
+		panic(&exit{status: status})
+	}
+
+	var result Result
+
+	func() {
+		defer func() {
+			if value := recover(); value != nil {
+				e, ok := value.(*exit)
+				if !ok {
+					panic(value)
+				}
+
+				result.ExitStatus = e.status
+			}
+		}()
+
+		p.ParseCommandLineArgs(test.Args)
+		p.Run()
+	}()
+
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+
+	return &result
+}
+
+func (r *Result) AssertGoldenStdout(t testing.TB, filePath string) {
+	t.Helper()
+	AssertGolden(t, filePath, r.Stdout)
+}
+
+func (r *Result) AssertGoldenStderr(t testing.TB, filePath string) {
+	t.Helper()
+	AssertGolden(t, filePath, r.Stderr)
+}
+
+func AssertGolden(t testing.TB, filePath, output string) {
+	t.Helper()
+
+	if os.Getenv(UpdateGoldenFilesEnvironmentVariable) != "" {
+		if err := writeGoldenFile(filePath, output); err != nil {
+			t.Fatalf("cannot update golden file: %v", err)
+		}
+
+		return
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("missing golden file %q (set %s to create it)",
+				filePath, UpdateGoldenFilesEnvironmentVariable)
+		}
+
+		t.Fatalf("cannot read golden file: %v", err)
+	}
+
+	if expected := string(data); output != expected {
+		t.Errorf("output does not match golden file %q\n"+
+			"--- expected\n%s\n--- actual\n%s", filePath, expected, output)
+	}
+}
+
+func writeGoldenFile(filePath, data string) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("cannot create directory: %w", err)
+	}
+
+	if err := os.WriteFile(filePath, []byte(data), 0644); err != nil {
+		return fmt.Errorf("cannot write %q: %w", filePath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package programtest
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.n16f.net/program"
+)
+
+func newTestProgram() *program.Program {
+	p := program.NewProgram("test", "a test program")
+
+	p.AddFlag("a", "flag-a", "a flag")
+	p.AddOption("c", "option-c", "value", "foo", "an option")
+
+	c := p.AddCommand("echo", "print arguments", func(p *program.Program) {
+		t := program.NewKeyValueTable()
+		t.AddRow("flag-a", p.IsOptionSet("flag-a"))
+		t.AddRow("option-c", p.OptionValue("option-c"))
+		t.AddRow("value", p.ArgumentValue("value"))
+		p.PrintTable(t)
+	})
+	c.AddArgument("value", "the value to print")
+
+	p.AddCommand("cat", "copy stdin to stdout", func(p *program.Program) {
+		if _, err := io.Copy(p.Stdout, p.Stdin); err != nil {
+			p.Fatal("cannot copy data: %v", err)
+		}
+	})
+
+	p.AddCommand("env", "print an environment variable", func(p *program.Program) {
+		p.Info("%s", program.EnvironmentVariable("TEST_VALUE"))
+	})
+
+	return p
+}
+
+func TestRun(t *testing.T) {
+	assert := assert.New(t)
+
+	p := newTestProgram()
+
+	res := Run(t, p, Test{Args: []string{"-a", "echo", "hello"}})
+	assert.Equal(0, res.ExitStatus)
+	assert.Equal("flag-a    true \noption-c  foo  \nvalue     hello\n",
+		res.Stdout)
+	assert.Equal("", res.Stderr)
+
+	res = Run(t, p, Test{Args: []string{"cat"}, Stdin: "hello\n"})
+	assert.Equal(0, res.ExitStatus)
+	assert.Equal("hello\n", res.Stdout)
+
+	res = Run(t, p, Test{
+		Args:        []string{"env"},
+		Environment: map[string]string{"TEST_VALUE": "hello"},
+	})
+	assert.Equal(0, res.ExitStatus)
+	assert.Equal("hello\n", res.Stderr)
+
+	res = Run(t, p, Test{Args: []string{"echo"}})
+	assert.Equal(1, res.ExitStatus)
+	assert.Equal("error: missing argument \"value\"\n", res.Stderr)
+}
+
+func TestRunHelp(t *testing.T) {
+	assert := assert.New(t)
+
+	p := newTestProgram()
+
+	res := Run(t, p, Test{Args: []string{"--help"}})
+	assert.Equal(0, res.ExitStatus)
+	res.AssertGoldenStderr(t, "testdata/help.txt")
+
+	res = Run(t, p, Test{Args: []string{"help", "echo"}})
+	assert.Equal(0, res.ExitStatus)
+	res.AssertGoldenStderr(t, "testdata/help-echo.txt")
+}
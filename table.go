@@ -2,6 +2,7 @@ package program
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -53,7 +54,16 @@ func (t *Table) AddRow(row ...interface{}) {
 }
 
 func (t *Table) Print() {
-	isTerminal := term.IsTerminal(int(os.Stdout.Fd()))
+	t.Write(os.Stdout, os.Stderr)
+}
+
+func (t *Table) Write(w, headerWriter io.Writer) {
+	// The header is only printed if the output is a terminal, and is written
+	// separately so that the output can be piped to other programs.
+	isTerminal := false
+	if f, ok := w.(*os.File); ok {
+		isTerminal = term.IsTerminal(int(f.Fd()))
+	}
 
 	rows := t.Render()
 	widths := t.columnWidths(rows)
@@ -61,7 +71,7 @@ func (t *Table) Print() {
 	if t.PrintHeader && isTerminal {
 		for i, c := range t.Columns {
 			if i > 0 {
-				fmt.Fprintf(os.Stderr, "  ")
+				fmt.Fprintf(headerWriter, "  ")
 			}
 
 			fmtString := "%-*s"
@@ -70,10 +80,10 @@ func (t *Table) Print() {
 			}
 
 			label := fmt.Sprintf(fmtString, widths[i], strings.ToUpper(c.Label))
-			fmt.Fprintf(os.Stderr, label)
+			fmt.Fprint(headerWriter, label)
 		}
 
-		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(headerWriter)
 	}
 
 	for _, row := range rows {
@@ -81,7 +91,7 @@ func (t *Table) Print() {
 			c := t.Columns[j]
 
 			if j > 0 {
-				fmt.Printf("  ")
+				fmt.Fprintf(w, "  ")
 			}
 
 			fmtString := "%-*s"
@@ -89,10 +99,10 @@ func (t *Table) Print() {
 				fmtString = "%*s"
 			}
 
-			fmt.Printf(fmtString, widths[j], s)
+			fmt.Fprintf(w, fmtString, widths[j], s)
 		}
 
-		fmt.Println("")
+		fmt.Fprintln(w, "")
 	}
 }
 
@@ -6,233 +6,552 @@ import (
 	"io"
 	"os"
 	"slices"
-	"sort"
+	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/exp/maps"
+	"golang.org/x/term"
 )
 
+// The width used to wrap usage text when the output is not a terminal.
+const defaultUsageWidth = 80
+
+// The minimal width of the description column; below, descriptions are
+// printed on the next line.
+const minUsageDescriptionWidth = 30
+
+// The usage model is the common representation of the documentation of a
+// command used by help messages and reference documentation, so that all
+// output formats are consistent.
+type usage struct {
+	Name            string
+	Line            string
+	Description     string
+	LongDescription []string
+
+	Examples []usageEntry
+	SeeAlso  []usageSeeAlso
+
+	CommandsLabel string
+	Commands      []usageCommand
+	Arguments     []usageEntry
+
+	OptionSections []usageOptionSection
+}
+
+type usageCommand struct {
+	usageEntry
+	Command *Command
+}
+
+type usageSeeAlso struct {
+	Name    string
+	Command *Command // nil if the reference does not match any command
+}
+
+type usageEntry struct {
+	Label       string
+	Description string
+}
+
+type usageOption struct {
+	usageEntry
+	Option *Option
+}
+
+type usageOptionSection struct {
+	Label   string
+	Options []usageOption
+}
+
 func (p *Program) PrintUsage(cmd *Command) {
-	// The logic here is convoluted. The "cmd" parameter is nil if the program
-	// has no commands, but is the top-level empty command (p.command) if the
-	// program has commands but usage is about the program and not a command.
-	// Ugly.
+	// The "cmd" parameter is nil if the program has no commands, but is the
+	// top-level empty command (p.command) if the program has commands but
+	// usage is about the program and not a command.
+
+	u := p.usage(cmd)
 
 	var buf bytes.Buffer
 
-	var hasCommands bool
-	var commands map[string]*Command
-	if cmd != nil {
-		hasCommands = len(cmd.subcommands) > 0
-		commands = cmd.subcommands
+	width := p.usageWidth()
+	maxWidth := u.maxLabelWidth()
+
+	fmt.Fprintf(&buf, "Usage: %s\n", u.Line)
+
+	// The help message of programs with commands only lists commands; the
+	// description of the program is only used in documentation.
+	isTopLevel := cmd != nil && cmd.FullName == ""
+
+	if u.Description != "" && !isTopLevel {
+		buf.WriteByte('\n')
+
+		for _, line := range wrapText(sentence(u.Description), width) {
+			fmt.Fprintf(&buf, "%s\n", line)
+		}
 	}
 
-	var arguments []*Argument
-	var description string
+	for _, paragraph := range u.LongDescription {
+		buf.WriteByte('\n')
 
-	if cmd == nil {
-		arguments = p.arguments
-		description = p.Description
-	} else {
-		arguments = cmd.arguments
-		description = cmd.Description
+		for _, line := range wrapText(paragraph, width) {
+			fmt.Fprintf(&buf, "%s\n", line)
+		}
 	}
 
-	hasArguments := len(arguments) > 0
+	if len(u.Commands) > 0 {
+		fmt.Fprintf(&buf, "\n%s\n\n", u.CommandsLabel)
 
-	maxWidth := p.computeMaxWidth(cmd)
+		for _, c := range u.Commands {
+			writeUsageEntry(&buf, c.usageEntry, maxWidth, width)
+		}
+	} else if len(u.Arguments) > 0 {
+		fmt.Fprintf(&buf, "\nARGUMENTS\n\n")
 
-	partialCommand := cmd != nil && cmd.FullName != ""
+		for _, arg := range u.Arguments {
+			writeUsageEntry(&buf, arg, maxWidth, width)
+		}
+	}
 
-	fmt.Fprintf(&buf, "Usage: %s", os.Args[0])
+	for _, section := range u.OptionSections {
+		fmt.Fprintf(&buf, "\n%s\n\n", section.Label)
 
-	if cmd == nil {
-		fmt.Fprintf(&buf, " [OPTIONS]")
-	} else {
-		fmt.Fprintf(&buf, " [GLOBAL OPTIONS]")
+		for _, opt := range section.Options {
+			writeUsageEntry(&buf, opt.usageEntry, maxWidth, width)
+		}
 	}
 
-	if partialCommand {
-		fmt.Fprintf(&buf, " %s", cmd.FullName)
+	if len(u.Examples) > 0 {
+		fmt.Fprintf(&buf, "\nEXAMPLES\n")
+
+		for _, example := range u.Examples {
+			fmt.Fprintf(&buf, "\n%s\n", example.Label)
+
+			for _, line := range wrapText(example.Description, width-4) {
+				fmt.Fprintf(&buf, "    %s\n", line)
+			}
+		}
 	}
 
-	if hasCommands && !hasArguments {
-		if partialCommand {
-			fmt.Fprintf(&buf, " SUBCOMMAND...")
+	if len(u.SeeAlso) > 0 {
+		names := make([]string, len(u.SeeAlso))
+		for i, ref := range u.SeeAlso {
+			names[i] = ref.Name
+		}
+
+		fmt.Fprintf(&buf, "\nSEE ALSO\n\n")
+
+		for _, line := range wrapText(strings.Join(names, ", "), width) {
+			fmt.Fprintf(&buf, "%s\n", line)
+		}
+	}
+
+	io.Copy(p.Stderr, &buf)
+}
+
+func (p *Program) usageWidth() int {
+	if f, ok := p.Stderr.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+			return width
+		}
+	}
+
+	return defaultUsageWidth
+}
+
+func writeUsageEntry(buf *bytes.Buffer, entry usageEntry, labelWidth, width int) {
+	// Descriptions are wrapped in a column after labels, with a hanging
+	// indent. If there is not enough space left for the column, descriptions
+	// start on the line after the label.
+	indent := labelWidth + 2
+
+	descriptionWidth := width - indent
+	if descriptionWidth < minUsageDescriptionWidth {
+		indent = 4
+		descriptionWidth = width - indent
+
+		fmt.Fprintf(buf, "%s\n", entry.Label)
+
+		for _, line := range wrapText(entry.Description, descriptionWidth) {
+			fmt.Fprintf(buf, "%*s%s\n", indent, "", line)
+		}
+
+		return
+	}
+
+	lines := wrapText(entry.Description, descriptionWidth)
+	if len(lines) == 0 {
+		fmt.Fprintf(buf, "%s\n", strings.TrimRight(entry.Label, " "))
+		return
+	}
+
+	for i, line := range lines {
+		if i == 0 {
+			fmt.Fprintf(buf, "%-*s  %s\n", labelWidth, entry.Label, line)
 		} else {
-			fmt.Fprintf(&buf, " COMMAND...")
+			fmt.Fprintf(buf, "%*s%s\n", indent, "", line)
+		}
+	}
+}
+
+func wrapText(s string, width int) []string {
+	var lines []string
+	var line strings.Builder
+	var lineLength int
+
+	for _, word := range strings.Fields(s) {
+		wordLength := utf8.RuneCountInString(word)
+
+		if lineLength > 0 && lineLength+1+wordLength > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			lineLength = 0
+		}
+
+		if lineLength > 0 {
+			line.WriteByte(' ')
+			lineLength++
 		}
+
+		line.WriteString(word)
+		lineLength += wordLength
 	}
 
-	if cmd != nil && cmd.Name != "" && hasArguments && len(cmd.options) > 0 {
-		fmt.Fprintf(&buf, " [COMMAND OPTIONS]")
+	if lineLength > 0 {
+		lines = append(lines, line.String())
+	}
+
+	return lines
+}
+
+func (p *Program) usage(cmd *Command) *usage {
+	u := usage{
+		Name: p.Name,
+		Line: p.usageLine(cmd),
 	}
 
-	if hasArguments {
-		for _, arg := range arguments {
-			if arg.Trailing {
-				fmt.Fprintf(&buf, " [<%s>...]", arg.Name)
-			} else if arg.Optional {
-				fmt.Fprintf(&buf, " [<%s>]", arg.Name)
-			} else {
-				fmt.Fprintf(&buf, " <%s>", arg.Name)
+	if cmd == nil || cmd.FullName == "" {
+		u.Description = p.Description
+	} else {
+		u.Name += " " + cmd.FullName
+		u.Description = cmd.Description
+		u.LongDescription = paragraphs(cmd.LongDescription)
+
+		for _, example := range cmd.Examples {
+			u.Examples = append(u.Examples, usageEntry{
+				Label:       p.Name + " " + example.CommandLine,
+				Description: example.Description,
+			})
+		}
+
+		for _, name := range cmd.SeeAlso {
+			ref := usageSeeAlso{
+				Name:    p.Name + " " + name,
+				Command: p.findCommand(splitCommandName(name)),
 			}
+
+			// Hidden commands are not documented, so references to them
+			// cannot be linked.
+			for c := ref.Command; c != nil; c = c.parent {
+				if c.Hidden {
+					ref.Command = nil
+					break
+				}
+			}
+
+			u.SeeAlso = append(u.SeeAlso, ref)
 		}
 	}
 
-	fmt.Fprintf(&buf, "\n")
+	if cmd != nil && len(cmd.subcommands) > 0 {
+		u.CommandsLabel = "COMMANDS"
+		if cmd.FullName != "" {
+			u.CommandsLabel = "SUBCOMMANDS"
+		}
 
-	if description != "" {
-		fmt.Fprintf(&buf, "\n%s\n", sentence(description))
-	}
+		names := maps.Keys(cmd.subcommands)
+		slices.Sort(names)
 
-	if hasCommands {
-		label := "COMMANDS"
-		if partialCommand {
-			label = "SUBCOMMANDS"
+		for _, name := range names {
+			subcmd := cmd.subcommands[name]
+			if subcmd.Hidden {
+				continue
+			}
+
+			var details []string
+
+			if len(subcmd.Aliases) > 0 {
+				label := "alias"
+				if len(subcmd.Aliases) > 1 {
+					label = "aliases"
+				}
+
+				details = append(details,
+					label+": "+strings.Join(subcmd.Aliases, ", "))
+			}
+
+			if subcmd.Deprecated != "" {
+				details = append(details, "deprecated")
+			}
+
+			description := subcmd.Description
+			if len(details) > 0 {
+				description += " (" + strings.Join(details, ", ") + ")"
+			}
+
+			u.Commands = append(u.Commands, usageCommand{
+				usageEntry: usageEntry{
+					Label:       subcmd.Label(),
+					Description: description,
+				},
+				Command: subcmd,
+			})
 		}
+	}
+
+	arguments := p.arguments
+	if cmd != nil {
+		arguments = cmd.arguments
+	}
 
-		p.usageCommands(&buf, label, commands, maxWidth)
-	} else if hasArguments {
-		p.usageArguments(&buf, arguments, maxWidth)
+	for _, arg := range arguments {
+		u.Arguments = append(u.Arguments, usageEntry{
+			Label:       arg.Name,
+			Description: arg.Description,
+		})
 	}
 
 	if len(p.options) > 0 {
-		if cmd == nil {
-			p.usageOptions(&buf, "OPTIONS", p.options, maxWidth)
-		} else {
-			p.usageOptions(&buf, "GLOBAL OPTIONS", p.options, maxWidth)
+		// Groups of global options are prefixed so that they cannot be
+		// confused with groups of command options with the same name.
+		label, groupPrefix := "OPTIONS", ""
+		if cmd != nil {
+			label, groupPrefix = "GLOBAL OPTIONS", "GLOBAL "
 		}
+
+		u.OptionSections = append(u.OptionSections,
+			p.usageOptionSections(label, groupPrefix, p.options)...)
+	}
+
+	if cmd != nil && len(cmd.allOptions()) > 0 {
+		u.OptionSections = append(u.OptionSections,
+			p.usageOptionSections("COMMAND OPTIONS", "",
+				cmd.allOptions())...)
 	}
 
-	if cmd != nil && len(cmd.options) > 0 {
-		p.usageOptions(&buf, "COMMAND OPTIONS", cmd.options, maxWidth)
+	return &u
+}
+
+func (s *usageOptionSection) entries() []usageEntry {
+	entries := make([]usageEntry, len(s.Options))
+	for i, opt := range s.Options {
+		entries[i] = opt.usageEntry
 	}
 
-	io.Copy(os.Stderr, &buf)
+	return entries
 }
 
-func (p *Program) computeMaxWidth(cmd *Command) int {
+func (u *usage) maxLabelWidth() int {
 	max := 0
 
-	if cmd != nil {
-		for _, subcmd := range cmd.subcommands {
-			if label := subcmd.Label(); len(label) > max {
-				max = len(label)
-			}
+	update := func(label string) {
+		if len(label) > max {
+			max = len(label)
+		}
+	}
+
+	for _, c := range u.Commands {
+		update(c.Label)
+	}
+
+	for _, arg := range u.Arguments {
+		update(arg.Label)
+	}
+
+	for _, section := range u.OptionSections {
+		for _, opt := range section.Options {
+			update(opt.Label)
 		}
 	}
 
-	var args []*Argument
+	return max
+}
+
+func (p *Program) usageLine(cmd *Command) string {
+	var buf bytes.Buffer
+
+	var hasCommands bool
+	var arguments []*Argument
+
 	if cmd == nil {
-		args = p.arguments
+		arguments = p.arguments
 	} else {
-		args = cmd.arguments
+		hasCommands = len(cmd.subcommands) > 0
+		arguments = cmd.arguments
 	}
 
-	for _, arg := range args {
-		if len(arg.Name) > max {
-			max = len(arg.Name)
-		}
+	hasArguments := len(arguments) > 0
+
+	partialCommand := cmd != nil && cmd.FullName != ""
+
+	buf.WriteString(p.Name)
+
+	if cmd == nil {
+		fmt.Fprintf(&buf, " [OPTIONS]")
+	} else {
+		fmt.Fprintf(&buf, " [GLOBAL OPTIONS]")
 	}
 
-	f := func(opt *Option) {
-		length := 2 + 2 + 2 + len(opt.LongName)
-		if opt.ValueName != "" {
-			length += 2 + len(opt.ValueName) + 1
-		}
+	s := p.optionConstraintsUsageString(p.options, p.optionConstraints)
+	if s != "" {
+		fmt.Fprintf(&buf, " %s", s)
+	}
 
-		if length > max {
-			max = length
+	if partialCommand {
+		fmt.Fprintf(&buf, " %s", cmd.FullName)
+	}
+
+	if hasCommands && !hasArguments {
+		if partialCommand {
+			fmt.Fprintf(&buf, " SUBCOMMAND...")
+		} else {
+			fmt.Fprintf(&buf, " COMMAND...")
 		}
 	}
 
-	for _, opt := range p.options {
-		f(opt)
+	if cmd != nil && cmd.Name != "" && hasArguments &&
+		len(cmd.allOptions()) > 0 {
+		fmt.Fprintf(&buf, " [COMMAND OPTIONS]")
 	}
 
 	if cmd != nil {
-		for _, opt := range cmd.options {
-			f(opt)
+		s := p.optionConstraintsUsageString(cmd.allOptions(),
+			cmd.allOptionConstraints())
+		if s != "" {
+			fmt.Fprintf(&buf, " %s", s)
 		}
 	}
 
-	return max
+	for _, arg := range arguments {
+		fmt.Fprintf(&buf, " %s", arg.usageString())
+	}
+
+	return buf.String()
 }
 
-func (p *Program) usageCommands(buf *bytes.Buffer, label string, commands map[string]*Command, maxWidth int) {
-	fmt.Fprintf(buf, "\n%s\n\n", label)
+func (p *Program) usageOptionSections(label, groupPrefix string, options map[string]*Option) []usageOptionSection {
+	// Options without a group are listed first in a section with the default
+	// label, followed by one section per group in the order in which groups
+	// were declared.
+	sortedOptions := uniqueOptions(options)
 
-	names := maps.Keys(commands)
-	slices.Sort(names)
+	declaredOptions := slices.Clone(sortedOptions)
+	slices.SortFunc(declaredOptions, func(opt1, opt2 *Option) int {
+		return opt1.index - opt2.index
+	})
 
-	for _, name := range names {
-		cmd := commands[name]
-		fmt.Fprintf(buf, "%-*s  %s\n", maxWidth, cmd.Label(), cmd.Description)
+	if p.PreserveOptionOrder {
+		sortedOptions = declaredOptions
 	}
-}
 
-func (p *Program) usageArguments(buf *bytes.Buffer, args []*Argument, maxWidth int) {
-	fmt.Fprintf(buf, "\nARGUMENTS\n\n")
+	sections := []usageOptionSection{{Label: label}}
+	sectionIndexes := make(map[string]int)
 
-	for _, arg := range args {
-		fmt.Fprintf(buf, "%-*s  %s\n", maxWidth, arg.Name, arg.Description)
-	}
-}
+	for _, opt := range declaredOptions {
+		if opt.Hidden {
+			continue
+		}
 
-func (p *Program) usageOptions(buf *bytes.Buffer, label string, options map[string]*Option, maxWidth int) {
-	fmt.Fprintf(buf, "\n%s\n\n", label)
+		if _, found := sectionIndexes[opt.Group]; !found && opt.Group != "" {
+			sectionIndexes[opt.Group] = len(sections)
 
-	strs := make(map[*Option]string)
+			sections = append(sections, usageOptionSection{
+				Label: groupPrefix + strings.ToUpper(opt.Group),
+			})
+		}
+	}
 
-	for _, opt := range options {
-		if _, found := strs[opt]; found {
+	for _, opt := range sortedOptions {
+		if opt.Hidden {
 			continue
 		}
 
-		buf := bytes.NewBuffer([]byte{})
+		i := sectionIndexes[opt.Group]
 
-		if opt.ShortName == "" {
-			fmt.Fprintf(buf, "  ")
-		} else {
-			fmt.Fprintf(buf, "-%s", opt.ShortName)
-		}
+		sections[i].Options = append(sections[i].Options, usageOption{
+			usageEntry: usageEntry{
+				Label:       opt.usageLabel(),
+				Description: p.optionDescription(opt),
+			},
+			Option: opt,
+		})
+	}
 
-		if opt.LongName != "" {
-			if opt.ShortName == "" {
-				buf.WriteString("  ")
-			} else {
-				buf.WriteString(", ")
-			}
+	if len(sections[0].Options) == 0 {
+		sections = sections[1:]
+	}
 
-			fmt.Fprintf(buf, "--%s", opt.LongName)
-		}
+	return sections
+}
 
-		if opt.ValueName != "" {
-			fmt.Fprintf(buf, " <%s>", opt.ValueName)
+func (opt *Option) usageLabel() string {
+	var buf bytes.Buffer
+
+	if opt.ShortName == "" {
+		buf.WriteString("    ")
+	} else {
+		fmt.Fprintf(&buf, "-%s", opt.ShortName)
+
+		if opt.LongName != "" {
+			buf.WriteString(", ")
 		}
+	}
 
-		str := buf.String()
-		strs[opt] = str
+	if opt.isNegatable() {
+		fmt.Fprintf(&buf, "--[no-]%s", opt.LongName)
+	} else if opt.LongName != "" {
+		fmt.Fprintf(&buf, "--%s", opt.LongName)
 	}
 
-	var opts []*Option
-	for opt, _ := range strs {
-		opts = append(opts, opt)
+	if opt.takesValue() {
+		fmt.Fprintf(&buf, " <%s>", opt.valueName())
+
+		if opt.Repeatable {
+			buf.WriteString("...")
+		}
 	}
 
-	sort.Slice(opts, func(i, j int) bool {
-		return opts[i].sortKey() < opts[j].sortKey()
-	})
+	return buf.String()
+}
 
-	for _, opt := range opts {
-		fmt.Fprintf(buf, "%-*s  %s", maxWidth, strs[opt], opt.Description)
+func (p *Program) optionDescription(opt *Option) string {
+	description := opt.Description
+	if details := p.optionDetails(opt); len(details) > 0 {
+		description += " (" + strings.Join(details, ", ") + ")"
+	}
 
-		if opt.DefaultValue != "" {
-			fmt.Fprintf(buf, " (default: %q)", opt.DefaultValue)
-		}
+	return description
+}
+
+func (p *Program) optionDetails(opt *Option) []string {
+	var details []string
+
+	if opt.Required {
+		details = append(details, "required")
+	}
+
+	if opt.DefaultValue != "" {
+		details = append(details, fmt.Sprintf("default: %q", opt.DefaultValue))
+	}
 
-		fmt.Fprintf(buf, "\n")
+	if name := p.optionEnvironmentVariable(opt); name != "" {
+		details = append(details, "env: "+name)
 	}
+
+	if opt.Deprecated != "" {
+		details = append(details, "deprecated")
+	}
+
+	return details
 }
 
 func (opt *Option) sortKey() string {
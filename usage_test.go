@@ -0,0 +1,206 @@
+package program
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapText(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		s     string
+		width int
+		lines []string
+	}{
+		{"", 10, nil},
+		{"foo", 10, []string{"foo"}},
+		{"foo bar baz", 11, []string{"foo bar baz"}},
+		{"foo bar baz", 10, []string{"foo bar", "baz"}},
+		{"foo  bar\nbaz", 3, []string{"foo", "bar", "baz"}},
+		{"foobarbaz foo", 5, []string{"foobarbaz", "foo"}},
+		{"éèà éèà", 7, []string{"éèà éèà"}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(test.lines, wrapText(test.s, test.width),
+			"%q (%d)", test.s, test.width)
+	}
+}
+
+func TestPrintUsageWrapping(t *testing.T) {
+	assert := assert.New(t)
+
+	var stderr bytes.Buffer
+
+	p := NewProgram("test", "a test program")
+	p.Stderr = &stderr
+
+	p.AddOption("o", "output", "path", "",
+		"the path of the file the output of the program is written to "+
+			"instead of the standard output")
+
+	p.PrintUsage(nil)
+
+	assert.Contains(stderr.String(), `
+-o, --output <path>  the path of the file the output of the program is written
+                     to instead of the standard output
+`)
+
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		assert.LessOrEqual(len(line), defaultUsageWidth)
+	}
+}
+
+func TestPrintUsageCommandDocumentation(t *testing.T) {
+	assert := assert.New(t)
+
+	var stderr bytes.Buffer
+
+	p := NewProgram("test", "a test program")
+	p.Stderr = &stderr
+
+	p.AddCommand("foo delete", "delete a foo", func(*Program) {})
+
+	c := p.AddCommand("foo create", "create a foo", func(*Program) {})
+	c.AddArgument("name", "the name of the foo")
+	c.LongDescription = `The foo is created in the current directory.
+
+Existing foos
+are never overwritten.`
+	c.Examples = []CommandExample{
+		{"foo create bar", "Create a foo named bar."},
+	}
+	c.SeeAlso = []string{"foo delete"}
+
+	p.PrintUsage(c)
+
+	assert.Equal(`Usage: test [GLOBAL OPTIONS] foo create <name>
+
+Create a foo.
+
+The foo is created in the current directory.
+
+Existing foos are never overwritten.
+
+ARGUMENTS
+
+name                 the name of the foo
+
+GLOBAL OPTIONS
+
+    --debug <level>  print debug messages (default: "0")
+-h, --help           print help and exit
+-q, --quiet          do not print status and information messages
+
+EXAMPLES
+
+test foo create bar
+    Create a foo named bar.
+
+SEE ALSO
+
+test foo delete
+`, stderr.String())
+}
+
+func TestPrintUsageOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	newProgram := func(stderr *bytes.Buffer) *Program {
+		p := NewProgram("test", "")
+		p.Stderr = stderr
+
+		p.AddOption("o", "output", "path", "", "the output file").
+			Group = "Output"
+		p.AddOption("", "port", "port", "", "the port").Group = "Network"
+		p.AddOption("", "address", "address", "", "the address").
+			Group = "Network"
+		p.AddFlag("c", "color", "colorize output").Group = "Output"
+		p.AddFlag("", "colour", "colorize output").Hidden = true
+		p.AddFlag("", "no-color", "disable colors").Deprecated = "unused"
+
+		return p
+	}
+
+	var stderr bytes.Buffer
+
+	newProgram(&stderr).PrintUsage(nil)
+
+	assert.Equal(`Usage: test [OPTIONS]
+
+OPTIONS
+
+    --debug <level>      print debug messages (default: "0")
+-h, --help               print help and exit
+    --no-color           disable colors (deprecated)
+-q, --quiet              do not print status and information messages
+
+OUTPUT
+
+-c, --color              colorize output
+-o, --output <path>      the output file
+
+NETWORK
+
+    --address <address>  the address
+    --port <port>        the port
+`, stderr.String())
+
+	stderr.Reset()
+
+	p := newProgram(&stderr)
+	p.PreserveOptionOrder = true
+	p.PrintUsage(nil)
+
+	assert.Equal(`Usage: test [OPTIONS]
+
+OPTIONS
+
+-h, --help               print help and exit
+-q, --quiet              do not print status and information messages
+    --debug <level>      print debug messages (default: "0")
+    --no-color           disable colors (deprecated)
+
+OUTPUT
+
+-o, --output <path>      the output file
+-c, --color              colorize output
+
+NETWORK
+
+    --port <port>        the port
+    --address <address>  the address
+`, stderr.String())
+
+	stderr.Reset()
+
+	p = NewProgram("test", "")
+	p.Stderr = &stderr
+	p.AddFlag("", "a", "global flag").Group = "Net"
+
+	c := p.AddCommand("foo", "", func(p *Program) {})
+	c.AddFlag("", "b", "command flag").Group = "Net"
+
+	p.PrintUsage(c)
+
+	assert.Equal(`Usage: test [GLOBAL OPTIONS] foo
+
+GLOBAL OPTIONS
+
+    --debug <level>  print debug messages (default: "0")
+-h, --help           print help and exit
+-q, --quiet          do not print status and information messages
+
+GLOBAL NET
+
+    --a              global flag
+
+NET
+
+    --b              command flag
+`, stderr.String())
+}
@@ -0,0 +1,487 @@
+package program
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.n16f.net/uuid"
+)
+
+var byteSizeRE = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*([a-zA-Z]*)$`)
+
+var byteSizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"pb":  1e15,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+}
+
+func parseBoolean(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	return false, fmt.Errorf("must be either %q or %q", "true", "false")
+}
+
+func parseRFC3339Datetime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be a valid RFC3339 datetime")
+	}
+
+	return t, nil
+}
+
+func parseUUID(s string) (uuid.UUID, error) {
+	var id uuid.UUID
+	if err := id.Parse(s); err != nil {
+		return id, fmt.Errorf("must be a valid UUID")
+	}
+
+	return id, nil
+}
+
+func parseInteger(s string, min, max int64) (int64, error) {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("must be an integer")
+	}
+
+	if i < min || i > max {
+		switch {
+		case min == math.MinInt64:
+			return 0, fmt.Errorf("must be lower than or equal to %d", max)
+		case max == math.MaxInt64:
+			return 0, fmt.Errorf("must be greater than or equal to %d", min)
+		default:
+			return 0, fmt.Errorf("must be between %d and %d", min, max)
+		}
+	}
+
+	return i, nil
+}
+
+func parseFloat(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0.0, fmt.Errorf("must be a number")
+	}
+
+	return f, nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("must be a valid duration (e.g. \"1h30m\")")
+	}
+
+	return d, nil
+}
+
+func parseByteSize(s string) (int64, error) {
+	matches := byteSizeRE.FindStringSubmatch(strings.TrimSpace(s))
+	if matches == nil {
+		return 0, fmt.Errorf("must be a valid size (e.g. \"10MiB\")")
+	}
+
+	unit, found := byteSizeUnits[strings.ToLower(matches[2])]
+	if !found {
+		return 0, fmt.Errorf("unknown size unit %q", matches[2])
+	}
+
+	f, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("must be a valid size (e.g. \"10MiB\")")
+	}
+
+	size := math.Round(f * unit)
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("size too large")
+	}
+
+	return int64(size), nil
+}
+
+func parseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" {
+		return nil, fmt.Errorf("must be a valid absolute URL")
+	}
+
+	return u, nil
+}
+
+func parseIPAddress(s string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("must be a valid IP address")
+	}
+
+	return addr, nil
+}
+
+func parseIPPrefix(s string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("must be a valid CIDR IP prefix")
+	}
+
+	return prefix, nil
+}
+
+func parseRegexp(s string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("must be a valid regular expression: %w", err)
+	}
+
+	return re, nil
+}
+
+func parseEnum(s string, values []string) (string, error) {
+	for _, value := range values {
+		if s == value {
+			return s, nil
+		}
+	}
+
+	quotedValues := make([]string, len(values))
+	for i, value := range values {
+		quotedValues[i] = strconv.Quote(value)
+	}
+
+	return "", fmt.Errorf("must be one of %s", strings.Join(quotedValues, ", "))
+}
+
+type Value interface {
+	// Parse is called for every value of the option or argument the value is
+	// associated with.
+	Parse(string) error
+
+	String() string
+
+	// The name of the type of the value, used in usage information for
+	// options which do not have a value name.
+	TypeName() string
+}
+
+// Values implementing ResettableValue are reset each time a command line is
+// parsed, so that the value of an option or argument which is not set does not
+// persist from one call to ParseArgs to the next. Reset must only clear the
+// parsed value and keep settings such as the bounds of an IntegerValue.
+type ResettableValue interface {
+	Value
+
+	Reset()
+}
+
+type StringValue struct {
+	Value string
+}
+
+func (v *StringValue) Parse(s string) error {
+	v.Value = s
+	return nil
+}
+
+func (v *StringValue) Reset() {
+	v.Value = ""
+}
+
+func (v *StringValue) String() string {
+	return v.Value
+}
+
+func (v *StringValue) TypeName() string {
+	return "string"
+}
+
+type BooleanValue struct {
+	Value bool
+}
+
+func (v *BooleanValue) Parse(s string) (err error) {
+	v.Value, err = parseBoolean(s)
+	return
+}
+
+func (v *BooleanValue) Reset() {
+	v.Value = false
+}
+
+func (v *BooleanValue) String() string {
+	return strconv.FormatBool(v.Value)
+}
+
+func (v *BooleanValue) TypeName() string {
+	return "boolean"
+}
+
+// If both Min and Max are zero, the value is not bounded.
+type IntegerValue struct {
+	Min int64
+	Max int64
+
+	Value int64
+}
+
+func (v *IntegerValue) Parse(s string) (err error) {
+	min, max := v.Min, v.Max
+	if min == 0 && max == 0 {
+		min, max = math.MinInt64, math.MaxInt64
+	}
+
+	v.Value, err = parseInteger(s, min, max)
+	return
+}
+
+func (v *IntegerValue) Reset() {
+	v.Value = 0
+}
+
+func (v *IntegerValue) String() string {
+	return strconv.FormatInt(v.Value, 10)
+}
+
+func (v *IntegerValue) TypeName() string {
+	return "integer"
+}
+
+type FloatValue struct {
+	Value float64
+}
+
+func (v *FloatValue) Parse(s string) (err error) {
+	v.Value, err = parseFloat(s)
+	return
+}
+
+func (v *FloatValue) Reset() {
+	v.Value = 0
+}
+
+func (v *FloatValue) String() string {
+	return strconv.FormatFloat(v.Value, 'g', -1, 64)
+}
+
+func (v *FloatValue) TypeName() string {
+	return "number"
+}
+
+type DurationValue struct {
+	Value time.Duration
+}
+
+func (v *DurationValue) Parse(s string) (err error) {
+	v.Value, err = parseDuration(s)
+	return
+}
+
+func (v *DurationValue) Reset() {
+	v.Value = 0
+}
+
+func (v *DurationValue) String() string {
+	return v.Value.String()
+}
+
+func (v *DurationValue) TypeName() string {
+	return "duration"
+}
+
+type ByteSizeValue struct {
+	Value int64
+}
+
+func (v *ByteSizeValue) Parse(s string) (err error) {
+	v.Value, err = parseByteSize(s)
+	return
+}
+
+func (v *ByteSizeValue) Reset() {
+	v.Value = 0
+}
+
+func (v *ByteSizeValue) String() string {
+	return strconv.FormatInt(v.Value, 10)
+}
+
+func (v *ByteSizeValue) TypeName() string {
+	return "size"
+}
+
+type RFC3339DatetimeValue struct {
+	Value time.Time
+}
+
+func (v *RFC3339DatetimeValue) Parse(s string) (err error) {
+	v.Value, err = parseRFC3339Datetime(s)
+	return
+}
+
+func (v *RFC3339DatetimeValue) Reset() {
+	v.Value = time.Time{}
+}
+
+func (v *RFC3339DatetimeValue) String() string {
+	return v.Value.Format(time.RFC3339Nano)
+}
+
+func (v *RFC3339DatetimeValue) TypeName() string {
+	return "datetime"
+}
+
+type UUIDValue struct {
+	Value uuid.UUID
+}
+
+func (v *UUIDValue) Parse(s string) (err error) {
+	v.Value, err = parseUUID(s)
+	return
+}
+
+func (v *UUIDValue) Reset() {
+	v.Value = uuid.UUID{}
+}
+
+func (v *UUIDValue) String() string {
+	return v.Value.String()
+}
+
+func (v *UUIDValue) TypeName() string {
+	return "uuid"
+}
+
+type URLValue struct {
+	Value *url.URL
+}
+
+func (v *URLValue) Parse(s string) (err error) {
+	v.Value, err = parseURL(s)
+	return
+}
+
+func (v *URLValue) Reset() {
+	v.Value = nil
+}
+
+func (v *URLValue) String() string {
+	if v.Value == nil {
+		return ""
+	}
+
+	return v.Value.String()
+}
+
+func (v *URLValue) TypeName() string {
+	return "url"
+}
+
+type IPAddressValue struct {
+	Value netip.Addr
+}
+
+func (v *IPAddressValue) Parse(s string) (err error) {
+	v.Value, err = parseIPAddress(s)
+	return
+}
+
+func (v *IPAddressValue) Reset() {
+	v.Value = netip.Addr{}
+}
+
+func (v *IPAddressValue) String() string {
+	return v.Value.String()
+}
+
+func (v *IPAddressValue) TypeName() string {
+	return "address"
+}
+
+type IPPrefixValue struct {
+	Value netip.Prefix
+}
+
+func (v *IPPrefixValue) Parse(s string) (err error) {
+	v.Value, err = parseIPPrefix(s)
+	return
+}
+
+func (v *IPPrefixValue) Reset() {
+	v.Value = netip.Prefix{}
+}
+
+func (v *IPPrefixValue) String() string {
+	return v.Value.String()
+}
+
+func (v *IPPrefixValue) TypeName() string {
+	return "prefix"
+}
+
+type RegexpValue struct {
+	Value *regexp.Regexp
+}
+
+func (v *RegexpValue) Parse(s string) (err error) {
+	v.Value, err = parseRegexp(s)
+	return
+}
+
+func (v *RegexpValue) Reset() {
+	v.Value = nil
+}
+
+func (v *RegexpValue) String() string {
+	if v.Value == nil {
+		return ""
+	}
+
+	return v.Value.String()
+}
+
+func (v *RegexpValue) TypeName() string {
+	return "regexp"
+}
+
+type EnumValue struct {
+	Values []string
+
+	Value string
+}
+
+func (v *EnumValue) Parse(s string) (err error) {
+	v.Value, err = parseEnum(s, v.Values)
+	return
+}
+
+func (v *EnumValue) Reset() {
+	v.Value = ""
+}
+
+func (v *EnumValue) String() string {
+	return v.Value
+}
+
+func (v *EnumValue) TypeName() string {
+	return strings.Join(v.Values, "|")
+}
@@ -0,0 +1,81 @@
+package program
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInteger(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		s        string
+		min, max int64
+		i        int64
+		err      string
+	}{
+		{"42", math.MinInt64, math.MaxInt64, 42, ""},
+		{"-3", math.MinInt64, math.MaxInt64, -3, ""},
+		{"1.5", math.MinInt64, math.MaxInt64, 0, "must be an integer"},
+		{"0", 1, 65535, 0, "must be between 1 and 65535"},
+		{"-1", 0, math.MaxInt64, 0, "must be greater than or equal to 0"},
+		{"11", math.MinInt64, 10, 0, "must be lower than or equal to 10"},
+	}
+
+	for _, test := range tests {
+		i, err := parseInteger(test.s, test.min, test.max)
+		if test.err == "" {
+			if assert.NoError(err, test.s) {
+				assert.Equal(test.i, i, test.s)
+			}
+		} else {
+			assert.EqualError(err, test.err, test.s)
+		}
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		s    string
+		size int64
+	}{
+		{"0", 0},
+		{"42", 42},
+		{"42B", 42},
+		{"10kB", 10_000},
+		{"10KiB", 10_240},
+		{"10 MiB", 10 * 1024 * 1024},
+		{"1.5GB", 1_500_000_000},
+		{"2tib", 2 * 1024 * 1024 * 1024 * 1024},
+	}
+
+	for _, test := range tests {
+		size, err := parseByteSize(test.s)
+		if assert.NoError(err, test.s) {
+			assert.Equal(test.size, size, test.s)
+		}
+	}
+
+	for _, s := range []string{"", "MiB", "-1", "10 foo", "1e3"} {
+		_, err := parseByteSize(s)
+		assert.Error(err, s)
+	}
+}
+
+func TestParseEnum(t *testing.T) {
+	assert := assert.New(t)
+
+	values := []string{"json", "text"}
+
+	s, err := parseEnum("json", values)
+	if assert.NoError(err) {
+		assert.Equal("json", s)
+	}
+
+	_, err = parseEnum("xml", values)
+	assert.EqualError(err, `must be one of "json", "text"`)
+}